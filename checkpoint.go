@@ -0,0 +1,110 @@
+package commando
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+)
+
+// Checkpoint records how far an Unmarshaller has read a file, so that
+// reading can resume from there with ResumeUnmarshaller.  It can be
+// saved as JSON.
+type Checkpoint struct {
+	// Offset is the byte offset of the end of the last row read.
+	Offset int64 `json:"offset"`
+
+	// Index is the number of rows read, not counting the header.
+	Index int `json:"index"`
+
+	// Line is the line the last row read ends on.
+	Line int `json:"line"`
+
+	// Headers are the file's headers.
+	Headers []string `json:"headers"`
+}
+
+// ErrNoOffset is returned by Checkpoint when the Reader doesn't
+// report byte offsets.
+var ErrNoOffset = errors.New("checkpoints need a PositionedReader with byte offsets, such as a csv.Reader")
+
+// Checkpoint returns a Checkpoint for the rows read so far.  It
+// mustn't be called while ReadAllCallback is reading with several
+// Workers.  Once ReadAllCallback returns, the Checkpoint is after the
+// last row handed to its callbacks, even if more rows were read ahead.
+// If Config.Unordered is set, that's the last row before the first
+// one which wasn't handed over, so resuming may repeat some rows.
+func (um *Unmarshaller) Checkpoint() (Checkpoint, error) {
+	checkpoint := um.position()
+	if um.delivered != nil {
+		checkpoint = *um.delivered
+	}
+	if checkpoint.Offset < 0 {
+		return Checkpoint{}, ErrNoOffset
+	}
+	checkpoint.Headers = append([]string(nil), um.config.fileHeaders()...)
+	return checkpoint, nil
+}
+
+// position returns a Checkpoint, without headers, for where the
+// Reader has got to.  Its Offset is -1 if the Reader doesn't report
+// byte offsets.
+func (um *Unmarshaller) position() Checkpoint {
+	offset := int64(-1)
+	if pr, ok := um.reader.(PositionedReader); ok {
+		offset = pr.InputOffset()
+	}
+	return Checkpoint{Offset: offset, Index: um.index, Line: um.endLine}
+}
+
+// ResumeUnmarshaller returns an Unmarshaller which reads rs from
+// where the Unmarshaller checkpoint was taken from stopped.  The
+// header isn't read again, and lines, offsets and indexes carry on
+// from the checkpoint.
+//
+// newReader returns the Reader for the rest of the file, such as a
+// csv.Reader with the original's options.  If it's nil, csv.NewReader
+// is used.
+func (c *Config) ResumeUnmarshaller(rs io.ReadSeeker, checkpoint Checkpoint, newReader func(io.Reader) Reader) (*Unmarshaller, error) {
+	if _, err := rs.Seek(checkpoint.Offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if newReader == nil {
+		newReader = func(r io.Reader) Reader { return csv.NewReader(r) }
+	}
+	reader := newReader(rs)
+	if pr, ok := reader.(PositionedReader); ok {
+		reader = &resumedReader{PositionedReader: pr, checkpoint: checkpoint}
+	}
+
+	headers := checkpoint.Headers
+	if len(c.Variants) > 0 {
+		headers = nil
+	}
+	vc, err := c.validate(headers)
+	if err != nil {
+		return nil, err
+	}
+	return &Unmarshaller{
+		reader:  reader,
+		config:  vc,
+		line:    checkpoint.Line,
+		index:   checkpoint.Index,
+		endLine: checkpoint.Line,
+	}, nil
+}
+
+// resumedReader is a PositionedReader for the rest of a file, which
+// reports positions in the whole file.
+type resumedReader struct {
+	PositionedReader
+	checkpoint Checkpoint
+}
+
+func (r *resumedReader) FieldPos(field int) (int, int) {
+	line, column := r.PositionedReader.FieldPos(field)
+	return line + r.checkpoint.Line, column
+}
+
+func (r *resumedReader) InputOffset() int64 {
+	return r.PositionedReader.InputOffset() + r.checkpoint.Offset
+}
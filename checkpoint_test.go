@@ -0,0 +1,134 @@
+package commando
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const checkpointCSV = "name,age\nann,1\n\"bo\nb\",2\ncy,3\ndee,x\n"
+
+// readMetas reads the rest of um, returning the RowMeta of each row
+// and the errors.
+func readMetas(t *testing.T, um *Unmarshaller) ([]RowMeta, []string) {
+	var metas []RowMeta
+	var errs []string
+	for {
+		_, meta, err := um.ReadWithMeta()
+		if err == io.EOF {
+			return metas, errs
+		}
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		metas = append(metas, meta)
+	}
+}
+
+func TestUnmarshaller_Checkpoint(t *testing.T) {
+	t.Parallel()
+
+	um, err := NewUnmarshaller(metaStruct{}, csv.NewReader(strings.NewReader(checkpointCSV)))
+	require.NoError(t, err)
+	allMetas, allErrs := readMetas(t, um)
+	require.Len(t, allMetas, 4)
+
+	um, err = NewUnmarshaller(metaStruct{}, csv.NewReader(strings.NewReader(checkpointCSV)))
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		_, err = um.Read()
+		require.NoError(t, err)
+	}
+	checkpoint, err := um.Checkpoint()
+	require.NoError(t, err)
+	assert.Equal(t, Checkpoint{Offset: 24, Index: 2, Line: 4, Headers: []string{"name", "age"}}, checkpoint)
+
+	data, err := json.Marshal(checkpoint)
+	require.NoError(t, err)
+	var restored Checkpoint
+	require.NoError(t, json.Unmarshal(data, &restored))
+
+	resumed, err := (&Config{Holder: metaStruct{}}).ResumeUnmarshaller(strings.NewReader(checkpointCSV), restored, nil)
+	require.NoError(t, err)
+	metas, errs := readMetas(t, resumed)
+	assert.Equal(t, allMetas[2:], metas)
+	assert.Equal(t, allErrs, errs)
+	assert.Equal(t, []string{`on line 6, column 5: cannot assign field "age" at index 1 through index chain [1]: strconv.ParseInt: parsing "x": invalid syntax`}, errs)
+}
+
+func TestUnmarshaller_CheckpointHeaderOnly(t *testing.T) {
+	t.Parallel()
+
+	um, err := NewUnmarshaller(metaStruct{}, csv.NewReader(strings.NewReader(checkpointCSV)))
+	require.NoError(t, err)
+	checkpoint, err := um.Checkpoint()
+	require.NoError(t, err)
+	assert.Equal(t, Checkpoint{Offset: 9, Index: 0, Line: 1, Headers: []string{"name", "age"}}, checkpoint)
+
+	um, err = NewUnmarshaller(raggedStruct{}, raggedRows())
+	require.NoError(t, err)
+	_, err = um.Checkpoint()
+	assert.Equal(t, ErrNoOffset, err)
+}
+
+type noteStruct struct {
+	Name string `csv:"name"`
+	Note string `csv:"note"`
+}
+
+func TestUnmarshaller_CheckpointMultiline(t *testing.T) {
+	t.Parallel()
+
+	// Quoted values span lines, including at the end of rows.
+	const data = "name,note\nann,\"one\ntwo\"\n\"b\no\",\"x,\"\"y\"\"\n\"\ncy,\"\n\"\ndee,last\n"
+
+	readRest := func(um *Unmarshaller) ([]interface{}, []RowMeta) {
+		var records []interface{}
+		var metas []RowMeta
+		for {
+			rec, meta, err := um.ReadWithMeta()
+			if err == io.EOF {
+				return records, metas
+			}
+			require.NoError(t, err)
+			records = append(records, rec)
+			metas = append(metas, meta)
+		}
+	}
+
+	um, err := NewUnmarshaller(noteStruct{}, csv.NewReader(strings.NewReader(data)))
+	require.NoError(t, err)
+	allRecords, allMetas := readRest(um)
+	require.Equal(t, []interface{}{
+		noteStruct{Name: "ann", Note: "one\ntwo"},
+		noteStruct{Name: "b\no", Note: "x,\"y\"\n"},
+		noteStruct{Name: "cy", Note: "\n"},
+		noteStruct{Name: "dee", Note: "last"},
+	}, allRecords)
+
+	for k := 0; k <= len(allRecords); k++ {
+		um, err := NewUnmarshaller(noteStruct{}, csv.NewReader(strings.NewReader(data)))
+		require.NoError(t, err)
+		for i := 0; i < k; i++ {
+			_, err = um.Read()
+			require.NoError(t, err)
+		}
+		checkpoint, err := um.Checkpoint()
+		require.NoError(t, err)
+
+		resumed, err := (&Config{Holder: noteStruct{}}).ResumeUnmarshaller(strings.NewReader(data), checkpoint, nil)
+		require.NoError(t, err)
+		records, metas := readRest(resumed)
+		if k == len(allRecords) {
+			assert.Empty(t, records)
+			continue
+		}
+		assert.Equal(t, allRecords[k:], records, "checkpoint after %d rows", k)
+		assert.Equal(t, allMetas[k:], metas, "checkpoint after %d rows", k)
+	}
+}
@@ -0,0 +1,679 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const commandoPath = "github.com/evenco/commando"
+
+// generate returns the source of a file declaring the CSV methods of
+// typeNames, which are declared in the package in dir.  The existing
+// file at output, if any, is ignored.
+func generate(dir string, typeNames []string, output string) ([]byte, error) {
+	pkg, err := loadPackage(dir, output)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{
+		pkg:     pkg,
+		imports: map[string]string{},
+	}
+	for _, name := range typeNames {
+		if err := g.generateType(name); err != nil {
+			return nil, err
+		}
+	}
+	return g.source()
+}
+
+// loadPackage parses and type-checks the package in dir, skipping the
+// file at exclude.
+func loadPackage(dir, exclude string) (*types.Package, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	excludeAbs, err := filepath.Abs(exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		path := filepath.Join(dir, name)
+		if abs, err := filepath.Abs(path); err == nil && abs == excludeAbs {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		// The package may refer to the methods being generated, so
+		// type errors are tolerated; fields with invalid types are
+		// reported when they're used.
+		Error: func(error) {},
+	}
+	pkg, _ := conf.Check(bp.ImportPath, fset, files, nil)
+	if pkg == nil {
+		return nil, fmt.Errorf("type-checking %s failed", dir)
+	}
+	return pkg, nil
+}
+
+// generator accumulates the generated code for a package.
+type generator struct {
+	pkg *types.Package
+
+	// imports maps the path of every imported package to its name.
+	imports map[string]string
+	buf     bytes.Buffer
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) use(path, name string) string {
+	g.imports[path] = name
+	return name
+}
+
+// typeString returns the Go expression for t in the generated file.
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string {
+		if p == g.pkg {
+			return ""
+		}
+		return g.use(p.Path(), p.Name())
+	})
+}
+
+func (g *generator) source() ([]byte, error) {
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by commando-gen. DO NOT EDIT.\n\npackage %s\n\n", g.pkg.Name())
+
+	paths := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	out.WriteString("import (\n")
+	for _, path := range paths {
+		fmt.Fprintf(&out, "\t%q\n", path)
+	}
+	out.WriteString(")\n")
+	out.Write(g.buf.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %v\n%s", err, out.Bytes())
+	}
+	return src, nil
+}
+
+// --------------------------------------------------------------------------
+// Fields
+
+// field is a struct field which is mapped to a CSV column.  It
+// mirrors commando's fieldInfo.
+type field struct {
+	keys      []string
+	omitEmpty bool
+	required  bool
+	chain     []int
+
+	// labels and values are the field's enum labels and the values
+	// they name, in tag order.
+	labels []string
+	values []string
+
+	// path is the chain of struct fields leading to this one,
+	// starting at the top-level struct.
+	path []*types.Var
+}
+
+func (f field) typ() types.Type {
+	return f.path[len(f.path)-1].Type()
+}
+
+func (f field) name() string {
+	names := make([]string, len(f.path))
+	for i, v := range f.path {
+		names[i] = v.Name()
+	}
+	return strings.Join(names, ".")
+}
+
+// selector returns the expression for the field, relative to x.
+func (f field) selector() string {
+	return "x." + f.name()
+}
+
+// parents returns the selectors of the embedded struct pointers along
+// the field's path, and their element types.
+func (f field) parents() ([]string, []types.Type) {
+	var selectors []string
+	var elems []types.Type
+	for i, v := range f.path[:len(f.path)-1] {
+		if ptr, ok := v.Type().Underlying().(*types.Pointer); ok {
+			selectors = append(selectors, field{path: f.path[:i+1]}.selector())
+			elems = append(elems, ptr.Elem())
+		}
+	}
+	return selectors, elems
+}
+
+// canMarshal mirrors commando's canMarshal: structs which have a
+// MarshalText or MarshalCSV method are a single value, rather than
+// having their fields mapped to columns.
+func canMarshal(t types.Type) bool {
+	mset := types.NewMethodSet(t)
+	return mset.Lookup(nil, "MarshalText") != nil || mset.Lookup(nil, "MarshalCSV") != nil
+}
+
+// fields mirrors commando's getFieldInfos.
+func fields(st *types.Struct, parentChain []int, parentPath []*types.Var) ([]field, error) {
+	var list []field
+	for i := 0; i < st.NumFields(); i++ {
+		v := st.Field(i)
+		if !v.Exported() {
+			continue
+		}
+
+		chain := append(append([]int(nil), parentChain...), i)
+		path := append(append([]*types.Var(nil), parentPath...), v)
+
+		// if the field is a pointer to a struct, follow the pointer then create a field for each field
+		if ptr, ok := v.Type().Underlying().(*types.Pointer); ok {
+			if inner, ok := ptr.Elem().Underlying().(*types.Struct); ok && !canMarshal(ptr.Elem()) {
+				embedded, err := fields(inner, chain, path)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, embedded...)
+			}
+		}
+		// if the field is a struct, create a field for each of its fields
+		if inner, ok := v.Type().Underlying().(*types.Struct); ok && !canMarshal(v.Type()) {
+			embedded, err := fields(inner, chain, path)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, embedded...)
+		}
+
+		// if the field is an embedded struct, ignore the csv tag
+		if v.Anonymous() {
+			continue
+		}
+
+		f := field{chain: chain, path: path}
+		tags := splitTag(reflect.StructTag(st.Tag(i)).Get("csv"))
+		filtered := []string{}
+		hasOption := false
+		for k, tag := range tags {
+			if tag == "omitempty" {
+				f.omitEmpty = true
+			} else if k > 0 && tag == "required" {
+				f.required = true
+			} else if j := strings.Index(tag, "="); j >= 0 {
+				if err := checkOption(tag[:j]); err != nil {
+					return nil, fmt.Errorf("field %s: %v", v.Name(), err)
+				}
+				if tag[:j] == "enum" {
+					if err := f.parseEnum(tag[j+1:]); err != nil {
+						return nil, fmt.Errorf("field %s: %v", v.Name(), err)
+					}
+				}
+				hasOption = true
+			} else if hasOption {
+				return nil, fmt.Errorf("field %s: key %q follows an option, keys must come first", v.Name(), tag)
+			} else {
+				filtered = append(filtered, tag)
+			}
+		}
+
+		if len(filtered) == 1 && filtered[0] == "-" {
+			continue
+		} else if len(filtered) > 0 && filtered[0] != "" {
+			f.keys = filtered
+		} else {
+			f.keys = []string{v.Name()}
+		}
+		list = append(list, f)
+	}
+	return list, nil
+}
+
+// parseEnum mirrors commando's parseEnum.
+func (f *field) parseEnum(spec string) error {
+	seen := map[string]bool{}
+	for _, entry := range strings.Split(spec, "|") {
+		i := strings.LastIndex(entry, ":")
+		if i <= 0 {
+			return fmt.Errorf("invalid enum entry %q, must be label:value", entry)
+		}
+		label, value := entry[:i], entry[i+1:]
+		if seen["label "+label] {
+			return fmt.Errorf("duplicate enum label %q", label)
+		}
+		if seen["value "+value] {
+			return fmt.Errorf("duplicate enum value %q", value)
+		}
+		seen["label "+label], seen["value "+value] = true, true
+		f.labels = append(f.labels, label)
+		f.values = append(f.values, value)
+	}
+	return nil
+}
+
+// splitTag splits a csv struct tag into its entries, like commando:
+// a regex option runs to the end of the tag.
+func splitTag(tag string) []string {
+	entries := strings.Split(tag, ",")
+	for k, entry := range entries {
+		if strings.HasPrefix(entry, "regex=") {
+			return append(entries[:k], strings.Join(entries[k:], ","))
+		}
+	}
+	return entries
+}
+
+// checkOption returns an error if name isn't a tag option commando
+// understands.
+func checkOption(name string) error {
+	switch name {
+	case "width", "align", "pad":
+		// Fixed-width layout options don't affect conversion.
+		return nil
+	case "enum":
+		return nil
+	case "oneof", "min", "max", "len", "maxlen", "regex":
+		// Validation rules are checked by commando after
+		// UnmarshalCSVRecord.
+		return nil
+	}
+	return fmt.Errorf("unknown option %q", name)
+}
+
+// --------------------------------------------------------------------------
+// Types
+
+func (g *generator) generateType(name string) error {
+	obj := g.pkg.Scope().Lookup(name)
+	if obj == nil {
+		return fmt.Errorf("type %s not found in package %s", name, g.pkg.Name())
+	}
+	named, ok := obj.Type().(*types.Named)
+	if !ok {
+		return fmt.Errorf("%s is not a named type", name)
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return fmt.Errorf("%s is not a struct", name)
+	}
+
+	list, err := fields(st, nil, nil)
+	if err != nil {
+		return fmt.Errorf("%s.%v", name, err)
+	}
+	if len(list) == 0 {
+		return fmt.Errorf("%s: no csv struct tags found", name)
+	}
+
+	commandoName := g.use(commandoPath, "commando")
+	g.printf("\nvar (\n")
+	g.printf("_ %s.RecordUnmarshaller = (*%s)(nil)\n", commandoName, name)
+	g.printf("_ %s.RecordMarshaller = (*%s)(nil)\n", commandoName, name)
+	g.printf(")\n")
+
+	g.generateFieldIndex(name, list)
+	if err := g.generateUnmarshal(name, list); err != nil {
+		return err
+	}
+	return g.generateMarshal(name, list)
+}
+
+// generateFieldIndex generates a function which maps a header to the
+// index of its field, like commando's getCSVFieldPosition.
+func (g *generator) generateFieldIndex(name string, list []field) {
+	stringsName := g.use("strings", "strings")
+
+	g.printf("\n// commandoField%s returns the index of the field of %s which header\n", name, name)
+	g.printf("// maps to, or -1.\n")
+	g.printf("func commandoField%s(header string) int {\n", name)
+	g.printf("exact, trimmed := commandoKey%[1]s(header), commandoKey%[1]s(%[2]s.TrimSpace(header))\n", name, stringsName)
+	g.printf("if exact < 0 || (trimmed >= 0 && trimmed < exact) {\nreturn trimmed\n}\n")
+	g.printf("return exact\n}\n")
+
+	g.printf("\nfunc commandoKey%s(key string) int {\n", name)
+	g.printf("switch key {\n")
+	seen := map[string]bool{}
+	for i, f := range list {
+		var keys []string
+		for _, key := range f.keys {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, strconv.Quote(key))
+			}
+		}
+		if len(keys) > 0 {
+			g.printf("case %s:\nreturn %d\n", strings.Join(keys, ", "), i)
+		}
+	}
+	g.printf("}\nreturn -1\n}\n")
+
+	g.printf("\nvar commandoChains%s = [][]int{\n", name)
+	for _, f := range list {
+		chain := make([]string, len(f.chain))
+		for i, x := range f.chain {
+			chain[i] = strconv.Itoa(x)
+		}
+		g.printf("{%s},\n", strings.Join(chain, ", "))
+	}
+	g.printf("}\n")
+}
+
+func (g *generator) generateUnmarshal(name string, list []field) error {
+	fmtName := g.use("fmt", "fmt")
+
+	g.printf("\n// UnmarshalCSVRecord sets the fields of x from row, whose columns are\n")
+	g.printf("// named by headers.\n")
+	g.printf("func (x *%s) UnmarshalCSVRecord(headers, row []string) error {\n", name)
+	g.printf("for j, value := range row {\n")
+	g.printf("if j >= len(headers) {\nbreak\n}\n")
+	g.printf("f := commandoField%s(headers[j])\n", name)
+	g.printf("var err error\n")
+	g.printf("switch f {\n")
+	for i, f := range list {
+		g.printf("case %d:\n", i)
+		selectors, elems := f.parents()
+		for k, sel := range selectors {
+			g.printf("if %s == nil {\n%s = new(%s)\n}\n", sel, sel, g.typeString(elems[k]))
+		}
+		if f.labels != nil {
+			g.decodeEnum(f)
+		}
+		if err := g.decode(f); err != nil {
+			return fmt.Errorf("%s.%s: %v", name, f.name(), err)
+		}
+		if f.labels != nil {
+			g.printf("}\n")
+		}
+	}
+	g.printf("}\n")
+	g.printf("if err != nil {\n")
+	g.printf("return &%s.ColumnError{Index: j, Err: %s.Errorf(\"cannot assign field %%q at index %%v through index chain %%v: %%w\", headers[j], j, commandoChains%s[f], err)}\n",
+		g.use(commandoPath, "commando"), fmtName, name)
+	g.printf("}\n}\nreturn nil\n}\n")
+	return nil
+}
+
+func (g *generator) generateMarshal(name string, list []field) error {
+	g.printf("\n// MarshalCSVRecord returns the values of the fields of x, in field order.\n")
+	g.printf("func (x *%s) MarshalCSVRecord() ([]string, error) {\n", name)
+	g.printf("row := make([]string, %d)\n", len(list))
+	for i, f := range list {
+		selectors, _ := f.parents()
+		if len(selectors) > 0 {
+			g.printf("if %s != nil {\n", strings.Join(selectors, " != nil && "))
+		}
+		if err := g.encode(f, fmt.Sprintf("row[%d]", i)); err != nil {
+			return fmt.Errorf("%s.%s: %v", name, f.name(), err)
+		}
+		if f.labels != nil {
+			g.encodeEnum(f, fmt.Sprintf("row[%d]", i))
+		}
+		if len(selectors) > 0 {
+			g.printf("}\n")
+		}
+	}
+	g.printf("return row, nil\n}\n")
+	return nil
+}
+
+// --------------------------------------------------------------------------
+// Conversions
+//
+// These mirror commando's compileDecoder and compileEncoder.
+
+// methodSignature returns the signature of the method name in the
+// method set of t, without parameter names, or "" if there is none.
+func methodSignature(t types.Type, name string) string {
+	sel := types.NewMethodSet(t).Lookup(nil, name)
+	if sel == nil {
+		return ""
+	}
+	sig := sel.Type().(*types.Signature)
+	unnamed := func(tuple *types.Tuple) *types.Tuple {
+		vars := make([]*types.Var, tuple.Len())
+		for i := range vars {
+			vars[i] = types.NewParam(token.NoPos, nil, "", tuple.At(i).Type())
+		}
+		return types.NewTuple(vars...)
+	}
+	return types.TypeString(types.NewSignature(nil, unnamed(sig.Params()), unnamed(sig.Results()), sig.Variadic()), nil)
+}
+
+// basicKind returns the kind of t's underlying basic type, or
+// types.Invalid.
+func basicKind(t types.Type) types.BasicKind {
+	if b, ok := t.Underlying().(*types.Basic); ok {
+		return b.Kind()
+	}
+	return types.Invalid
+}
+
+// parseFunc returns the commando function which parses values of
+// the basic kind, and the type it returns.
+func parseFunc(kind types.BasicKind) (string, string, bool) {
+	switch kind {
+	case types.Bool:
+		return "ParseBool", "bool", true
+	case types.Int, types.Int8, types.Int16, types.Int32, types.Int64:
+		return "ParseInt", "int64", true
+	case types.Uint, types.Uint8, types.Uint16, types.Uint32, types.Uint64:
+		return "ParseUint", "uint64", true
+	case types.Float32, types.Float64:
+		return "ParseFloat", "float64", true
+	}
+	return "", "", false
+}
+
+// decodeEnum generates code which replaces a label in value with
+// the value it names, like commando's enumDecoder.  It opens a block
+// in which to decode value, which the caller closes.
+func (g *generator) decodeEnum(f field) {
+	stringsName := g.use("strings", "strings")
+	g.printf("switch %s.TrimSpace(value) {\n", stringsName)
+	g.printf("case \"\":\n")
+	quoted := make([]string, len(f.labels))
+	for i, label := range f.labels {
+		quoted[i] = strconv.Quote(label)
+		g.printf("case %s:\nvalue = %s\n", quoted[i], strconv.Quote(f.values[i]))
+	}
+	g.printf("default:\n")
+	g.printf("err = %s.Errorf(\"unknown label %%q, expected one of %%q\", %s.TrimSpace(value), []string{%s})\n",
+		g.use("fmt", "fmt"), stringsName, strings.Join(quoted, ", "))
+	g.printf("}\n")
+	g.printf("if err == nil {\n")
+}
+
+// encodeEnum generates code which replaces the value in out with its
+// label, like commando's enumEncoder.
+func (g *generator) encodeEnum(f field, out string) {
+	g.printf("switch %s {\n", out)
+	g.printf("case \"\":\n")
+	for i, value := range f.values {
+		g.printf("case %s:\n%s = %s\n", strconv.Quote(value), out, strconv.Quote(f.labels[i]))
+	}
+	g.printf("default:\n")
+	g.printf("return nil, %s.Errorf(\"value %%q has no label\", %s)\n", g.use("fmt", "fmt"), out)
+	g.printf("}\n")
+}
+
+func (g *generator) decode(f field) error {
+	t := f.typ()
+	sel := f.selector()
+	ptr, ok := t.Underlying().(*types.Pointer)
+	if !ok {
+		return g.decodeValue(t, sel, sel, "&"+sel, f.labels == nil)
+	}
+
+	elem := ptr.Elem()
+	switch elem.Underlying().(type) {
+	case *types.Pointer, *types.Interface:
+		return fmt.Errorf("type %s is not supported", t)
+	}
+	if f.omitEmpty {
+		g.printf("if value != \"\" {\n")
+	}
+	g.printf("if %s == nil {\n%s = new(%s)\n}\n", sel, sel, g.typeString(elem))
+	if err := g.decodeValue(elem, sel, "*"+sel, sel, f.labels == nil); err != nil {
+		return err
+	}
+	if f.omitEmpty {
+		g.printf("}\n")
+	}
+	return nil
+}
+
+// decodeValue generates code which sets target, of type t, from
+// value.  sel is the selector of the field holding target, and addr
+// is the address of target.  Unmarshalling methods are only used if
+// methods is set, since enums decode their values by kind.
+func (g *generator) decodeValue(t types.Type, sel, target, addr string, methods bool) error {
+	if t.Underlying() == types.Typ[types.Invalid] {
+		return fmt.Errorf("invalid type")
+	}
+
+	_, predeclared := t.(*types.Basic)
+	if !predeclared && methods {
+		ptr := types.NewPointer(t)
+		if methodSignature(ptr, "UnmarshalCSV") == "func(string) error" {
+			g.printf("err = %s.UnmarshalCSV(value)\n", sel)
+			return nil
+		}
+		if methodSignature(ptr, "UnmarshalText") == "func([]byte) error" {
+			g.printf("err = %s.UnmarshalText([]byte(value))\n", sel)
+			return nil
+		}
+	}
+
+	kind := basicKind(t)
+	if kind == types.String {
+		if predeclared {
+			g.printf("%s = value\n", target)
+		} else {
+			g.printf("%s = %s(value)\n", target, g.typeString(t))
+		}
+		return nil
+	}
+	if parse, result, ok := parseFunc(kind); ok {
+		g.printf("var v %s\n", result)
+		g.printf("if v, err = %s.%s(value); err == nil {\n", g.use(commandoPath, "commando"), parse)
+		g.printf("%s = %s(v)\n}\n", target, g.typeString(t))
+		return nil
+	}
+
+	switch t.Underlying().(type) {
+	case *types.Slice, *types.Struct:
+		g.printf("err = %s.Unmarshal([]byte(value), %s)\n", g.use("encoding/json", "json"), addr)
+		return nil
+	}
+	return fmt.Errorf("type %s is not supported", t)
+}
+
+func (g *generator) encode(f field, out string) error {
+	t := f.typ()
+	sel := f.selector()
+	ptr, ok := t.Underlying().(*types.Pointer)
+	if !ok {
+		return g.encodeValue(t, sel, sel, out, f.labels == nil)
+	}
+
+	elem := ptr.Elem()
+	switch elem.Underlying().(type) {
+	case *types.Pointer, *types.Interface:
+		return fmt.Errorf("type %s is not supported", t)
+	}
+	g.printf("if %s != nil {\n", sel)
+	if err := g.encodeValue(elem, sel, "*"+sel, out, f.labels == nil); err != nil {
+		return err
+	}
+	g.printf("}\n")
+	return nil
+}
+
+// encodeValue generates code which sets out to the CSV representation
+// of value, of type t.  sel is the selector of the field holding
+// value.  Marshalling methods are only used if methods is set, since
+// enums look up their values by kind.
+func (g *generator) encodeValue(t types.Type, sel, value, out string, methods bool) error {
+	if t.Underlying() == types.Typ[types.Invalid] {
+		return fmt.Errorf("invalid type")
+	}
+	if _, ok := t.Underlying().(*types.Interface); ok {
+		return fmt.Errorf("type %s is not supported", t)
+	}
+
+	// Records are always marshalled through a pointer, so the
+	// methods of *T are available.
+	if _, predeclared := t.(*types.Basic); !predeclared && methods {
+		ptr := types.NewPointer(t)
+		switch {
+		case methodSignature(ptr, "MarshalCSV") == "func() (string, error)":
+			g.printf("{\nvar err error\nif %s, err = %s.MarshalCSV(); err != nil {\nreturn nil, err\n}\n}\n", out, sel)
+			return nil
+		case methodSignature(ptr, "MarshalText") == "func() ([]byte, error)":
+			g.printf("{\ntext, err := %s.MarshalText()\nif err != nil {\nreturn nil, err\n}\n%s = string(text)\n}\n", sel, out)
+			return nil
+		case methodSignature(ptr, "String") == "func() string":
+			g.printf("%s = %s.String()\n", out, sel)
+			return nil
+		}
+	}
+
+	switch kind := basicKind(t); kind {
+	case types.String:
+		if _, predeclared := t.(*types.Basic); predeclared {
+			g.printf("%s = %s\n", out, value)
+		} else {
+			g.printf("%s = string(%s)\n", out, value)
+		}
+	case types.Bool:
+		g.printf("%s = %s.FormatBool(bool(%s))\n", out, g.use("strconv", "strconv"), value)
+	case types.Int, types.Int8, types.Int16, types.Int32, types.Int64:
+		g.printf("%s = %s.FormatInt(int64(%s), 10)\n", out, g.use("strconv", "strconv"), value)
+	case types.Uint, types.Uint8, types.Uint16, types.Uint32, types.Uint64:
+		g.printf("%s = %s.FormatUint(uint64(%s), 10)\n", out, g.use("strconv", "strconv"), value)
+	case types.Float32:
+		g.printf("%s = %s.FormatFloat(float64(%s), 'f', -1, 32)\n", out, g.use("strconv", "strconv"), value)
+	case types.Float64:
+		g.printf("%s = %s.FormatFloat(float64(%s), 'f', -1, 64)\n", out, g.use("strconv", "strconv"), value)
+	default:
+		// Like getFieldAsString, values with no conversion are
+		// written as empty cells.
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate_UpToDate(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join("internal", "sample")
+	output := filepath.Join(dir, "sample_csv.go")
+	src, err := generate(dir, []string{"Order", "Customer"}, output)
+	require.NoError(t, err)
+
+	want, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(src), "sample_csv.go is stale; run go generate ./...")
+}
+
+func TestGenerate_Errors(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join("internal", "sample")
+	output := filepath.Join(dir, "sample_csv.go")
+
+	_, err := generate(dir, []string{"Missing"}, output)
+	assert.EqualError(t, err, "type Missing not found in package sample")
+
+	_, err = generate(dir, []string{"Status"}, output)
+	assert.EqualError(t, err, "Status is not a struct")
+}
@@ -0,0 +1,71 @@
+// Package sample exercises the code generated by commando-gen.
+package sample
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:generate go run github.com/evenco/commando/cmd/commando-gen -type=Order,Customer -output=sample_csv.go
+
+// Status is a renamed basic type.
+type Status string
+
+// Stage is an enum whose String method doesn't give its labels.
+type Stage int
+
+func (s Stage) String() string {
+	return "Stage(" + strconv.Itoa(int(s)) + ")"
+}
+
+// Code has its own CSV conversion.
+type Code struct {
+	Value string
+}
+
+func (c *Code) UnmarshalCSV(s string) error {
+	c.Value = strings.ToUpper(s)
+	return nil
+}
+
+func (c Code) MarshalCSV() (string, error) {
+	return strings.ToLower(c.Value), nil
+}
+
+// Address is embedded by pointer.
+type Address struct {
+	Street string `csv:"street"`
+	City   string `csv:"city,town"`
+}
+
+// Audit is embedded by value.
+type Audit struct {
+	CreatedBy string    `csv:"created_by"`
+	CreatedAt time.Time `csv:"created_at"`
+}
+
+type Customer struct {
+	ID    int64  `csv:"customer_id,id"`
+	Name  string `csv:"name"`
+	Email string
+	*Address
+	Audit
+	Notes string `csv:"-"`
+	note  string
+}
+
+type Order struct {
+	ID       uint32     `csv:"order_id,required"`
+	Customer Customer   `csv:"-"`
+	Amount   float64    `csv:"amount,width=10"`
+	Discount float32    `csv:"discount"`
+	Paid     bool       `csv:"paid"`
+	Status   Status     `csv:"status,oneof=open|closed"`
+	Code     Code       `csv:"code,regex=^[a-z]{1,3}$"`
+	Shipped  *time.Time `csv:"shipped,omitempty"`
+	Quantity *int       `csv:"quantity"`
+	Tags     []string   `csv:"tags"`
+	Priority int8       `csv:"priority"`
+	Stage    Stage      `csv:"stage,enum=new:0|done:1"`
+}
@@ -0,0 +1,359 @@
+// Code generated by commando-gen. DO NOT EDIT.
+
+package sample
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/evenco/commando"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	_ commando.RecordUnmarshaller = (*Order)(nil)
+	_ commando.RecordMarshaller   = (*Order)(nil)
+)
+
+// commandoFieldOrder returns the index of the field of Order which header
+// maps to, or -1.
+func commandoFieldOrder(header string) int {
+	exact, trimmed := commandoKeyOrder(header), commandoKeyOrder(strings.TrimSpace(header))
+	if exact < 0 || (trimmed >= 0 && trimmed < exact) {
+		return trimmed
+	}
+	return exact
+}
+
+func commandoKeyOrder(key string) int {
+	switch key {
+	case "order_id":
+		return 0
+	case "customer_id", "id":
+		return 1
+	case "name":
+		return 2
+	case "Email":
+		return 3
+	case "street":
+		return 4
+	case "city", "town":
+		return 5
+	case "created_by":
+		return 6
+	case "created_at":
+		return 7
+	case "amount":
+		return 8
+	case "discount":
+		return 9
+	case "paid":
+		return 10
+	case "status":
+		return 11
+	case "code":
+		return 12
+	case "shipped":
+		return 13
+	case "quantity":
+		return 14
+	case "tags":
+		return 15
+	case "priority":
+		return 16
+	case "stage":
+		return 17
+	}
+	return -1
+}
+
+var commandoChainsOrder = [][]int{
+	{0},
+	{1, 0},
+	{1, 1},
+	{1, 2},
+	{1, 3, 0},
+	{1, 3, 1},
+	{1, 4, 0},
+	{1, 4, 1},
+	{2},
+	{3},
+	{4},
+	{5},
+	{6},
+	{7},
+	{8},
+	{9},
+	{10},
+	{11},
+}
+
+// UnmarshalCSVRecord sets the fields of x from row, whose columns are
+// named by headers.
+func (x *Order) UnmarshalCSVRecord(headers, row []string) error {
+	for j, value := range row {
+		if j >= len(headers) {
+			break
+		}
+		f := commandoFieldOrder(headers[j])
+		var err error
+		switch f {
+		case 0:
+			var v uint64
+			if v, err = commando.ParseUint(value); err == nil {
+				x.ID = uint32(v)
+			}
+		case 1:
+			var v int64
+			if v, err = commando.ParseInt(value); err == nil {
+				x.Customer.ID = int64(v)
+			}
+		case 2:
+			x.Customer.Name = value
+		case 3:
+			x.Customer.Email = value
+		case 4:
+			if x.Customer.Address == nil {
+				x.Customer.Address = new(Address)
+			}
+			x.Customer.Address.Street = value
+		case 5:
+			if x.Customer.Address == nil {
+				x.Customer.Address = new(Address)
+			}
+			x.Customer.Address.City = value
+		case 6:
+			x.Customer.Audit.CreatedBy = value
+		case 7:
+			err = x.Customer.Audit.CreatedAt.UnmarshalText([]byte(value))
+		case 8:
+			var v float64
+			if v, err = commando.ParseFloat(value); err == nil {
+				x.Amount = float64(v)
+			}
+		case 9:
+			var v float64
+			if v, err = commando.ParseFloat(value); err == nil {
+				x.Discount = float32(v)
+			}
+		case 10:
+			var v bool
+			if v, err = commando.ParseBool(value); err == nil {
+				x.Paid = bool(v)
+			}
+		case 11:
+			x.Status = Status(value)
+		case 12:
+			err = x.Code.UnmarshalCSV(value)
+		case 13:
+			if value != "" {
+				if x.Shipped == nil {
+					x.Shipped = new(time.Time)
+				}
+				err = x.Shipped.UnmarshalText([]byte(value))
+			}
+		case 14:
+			if x.Quantity == nil {
+				x.Quantity = new(int)
+			}
+			var v int64
+			if v, err = commando.ParseInt(value); err == nil {
+				*x.Quantity = int(v)
+			}
+		case 15:
+			err = json.Unmarshal([]byte(value), &x.Tags)
+		case 16:
+			var v int64
+			if v, err = commando.ParseInt(value); err == nil {
+				x.Priority = int8(v)
+			}
+		case 17:
+			switch strings.TrimSpace(value) {
+			case "":
+			case "new":
+				value = "0"
+			case "done":
+				value = "1"
+			default:
+				err = fmt.Errorf("unknown label %q, expected one of %q", strings.TrimSpace(value), []string{"new", "done"})
+			}
+			if err == nil {
+				var v int64
+				if v, err = commando.ParseInt(value); err == nil {
+					x.Stage = Stage(v)
+				}
+			}
+		}
+		if err != nil {
+			return &commando.ColumnError{Index: j, Err: fmt.Errorf("cannot assign field %q at index %v through index chain %v: %w", headers[j], j, commandoChainsOrder[f], err)}
+		}
+	}
+	return nil
+}
+
+// MarshalCSVRecord returns the values of the fields of x, in field order.
+func (x *Order) MarshalCSVRecord() ([]string, error) {
+	row := make([]string, 18)
+	row[0] = strconv.FormatUint(uint64(x.ID), 10)
+	row[1] = strconv.FormatInt(int64(x.Customer.ID), 10)
+	row[2] = x.Customer.Name
+	row[3] = x.Customer.Email
+	if x.Customer.Address != nil {
+		row[4] = x.Customer.Address.Street
+	}
+	if x.Customer.Address != nil {
+		row[5] = x.Customer.Address.City
+	}
+	row[6] = x.Customer.Audit.CreatedBy
+	{
+		text, err := x.Customer.Audit.CreatedAt.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		row[7] = string(text)
+	}
+	row[8] = strconv.FormatFloat(float64(x.Amount), 'f', -1, 64)
+	row[9] = strconv.FormatFloat(float64(x.Discount), 'f', -1, 32)
+	row[10] = strconv.FormatBool(bool(x.Paid))
+	row[11] = string(x.Status)
+	{
+		var err error
+		if row[12], err = x.Code.MarshalCSV(); err != nil {
+			return nil, err
+		}
+	}
+	if x.Shipped != nil {
+		{
+			text, err := x.Shipped.MarshalText()
+			if err != nil {
+				return nil, err
+			}
+			row[13] = string(text)
+		}
+	}
+	if x.Quantity != nil {
+		row[14] = strconv.FormatInt(int64(*x.Quantity), 10)
+	}
+	row[16] = strconv.FormatInt(int64(x.Priority), 10)
+	row[17] = strconv.FormatInt(int64(x.Stage), 10)
+	switch row[17] {
+	case "":
+	case "0":
+		row[17] = "new"
+	case "1":
+		row[17] = "done"
+	default:
+		return nil, fmt.Errorf("value %q has no label", row[17])
+	}
+	return row, nil
+}
+
+var (
+	_ commando.RecordUnmarshaller = (*Customer)(nil)
+	_ commando.RecordMarshaller   = (*Customer)(nil)
+)
+
+// commandoFieldCustomer returns the index of the field of Customer which header
+// maps to, or -1.
+func commandoFieldCustomer(header string) int {
+	exact, trimmed := commandoKeyCustomer(header), commandoKeyCustomer(strings.TrimSpace(header))
+	if exact < 0 || (trimmed >= 0 && trimmed < exact) {
+		return trimmed
+	}
+	return exact
+}
+
+func commandoKeyCustomer(key string) int {
+	switch key {
+	case "customer_id", "id":
+		return 0
+	case "name":
+		return 1
+	case "Email":
+		return 2
+	case "street":
+		return 3
+	case "city", "town":
+		return 4
+	case "created_by":
+		return 5
+	case "created_at":
+		return 6
+	}
+	return -1
+}
+
+var commandoChainsCustomer = [][]int{
+	{0},
+	{1},
+	{2},
+	{3, 0},
+	{3, 1},
+	{4, 0},
+	{4, 1},
+}
+
+// UnmarshalCSVRecord sets the fields of x from row, whose columns are
+// named by headers.
+func (x *Customer) UnmarshalCSVRecord(headers, row []string) error {
+	for j, value := range row {
+		if j >= len(headers) {
+			break
+		}
+		f := commandoFieldCustomer(headers[j])
+		var err error
+		switch f {
+		case 0:
+			var v int64
+			if v, err = commando.ParseInt(value); err == nil {
+				x.ID = int64(v)
+			}
+		case 1:
+			x.Name = value
+		case 2:
+			x.Email = value
+		case 3:
+			if x.Address == nil {
+				x.Address = new(Address)
+			}
+			x.Address.Street = value
+		case 4:
+			if x.Address == nil {
+				x.Address = new(Address)
+			}
+			x.Address.City = value
+		case 5:
+			x.Audit.CreatedBy = value
+		case 6:
+			err = x.Audit.CreatedAt.UnmarshalText([]byte(value))
+		}
+		if err != nil {
+			return &commando.ColumnError{Index: j, Err: fmt.Errorf("cannot assign field %q at index %v through index chain %v: %w", headers[j], j, commandoChainsCustomer[f], err)}
+		}
+	}
+	return nil
+}
+
+// MarshalCSVRecord returns the values of the fields of x, in field order.
+func (x *Customer) MarshalCSVRecord() ([]string, error) {
+	row := make([]string, 7)
+	row[0] = strconv.FormatInt(int64(x.ID), 10)
+	row[1] = x.Name
+	row[2] = x.Email
+	if x.Address != nil {
+		row[3] = x.Address.Street
+	}
+	if x.Address != nil {
+		row[4] = x.Address.City
+	}
+	row[5] = x.Audit.CreatedBy
+	{
+		text, err := x.Audit.CreatedAt.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		row[6] = string(text)
+	}
+	return row, nil
+}
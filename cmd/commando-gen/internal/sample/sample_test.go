@@ -0,0 +1,167 @@
+package sample
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evenco/commando"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reflectedOrder has the same fields as Order, but none of its
+// methods, so commando handles it with reflection.
+type reflectedOrder Order
+
+const orderCSV = `order_id,id, name,Email,town,street,created_by,created_at,amount,discount,paid,status,code,shipped,quantity,tags,priority,stage,unknown
+1,42,Ann,ann@example.com,Paris,Rue 1,bob,2020-01-02T03:04:05Z,10.5,0.25,yes,open,abc,2020-02-03T00:00:00Z,3,"[""a"",""b""]",-7,done,x
+2,,,,,,,2020-01-02T00:00:00Z,,,,,,,,null,
+3,1.9,Bo,,,,,2020-01-02T00:00:00Z,,,no,,,,0,null,
+4,x
+5,1,,,,,,not a time
+6,1,,,,,,2020-01-02T00:00:00Z,,,,,,,,[,
+7,1,,,,,,2020-01-02T00:00:00Z,,,,,,not a time
+8,1,,,,,,2020-01-02T00:00:00Z,,,,,,,,null,300
+9,1,,,,,,2020-01-02T00:00:00Z,,,,lost,,,,null,
+10,1,,,,,,2020-01-02T00:00:00Z,,,,,,,,null,,later
+`
+
+func readAll(t *testing.T, holder interface{}) ([]interface{}, []string) {
+	um, err := commando.NewUnmarshaller(holder, newReader(orderCSV))
+	require.NoError(t, err)
+
+	var records []interface{}
+	var errs []string
+	for {
+		rec, err := um.Read()
+		if err == io.EOF {
+			return records, errs
+		}
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		records = append(records, rec)
+	}
+}
+
+func newReader(s string) *csv.Reader {
+	r := csv.NewReader(strings.NewReader(s))
+	r.FieldsPerRecord = -1
+	return r
+}
+
+func TestUnmarshalCSVRecord(t *testing.T) {
+	t.Parallel()
+
+	generated, generatedErrs := readAll(t, Order{})
+	reflected, reflectedErrs := readAll(t, reflectedOrder{})
+
+	require.Len(t, generated, len(reflected))
+	for i := range generated {
+		assert.Equal(t, Order(reflected[i].(reflectedOrder)), generated[i], "record %d", i)
+	}
+	assert.Equal(t, reflectedErrs, generatedErrs)
+	assert.Len(t, generatedErrs, 6)
+	assert.Equal(t, `on line 10, column 34: field "status": value "lost" does not satisfy oneof=open|closed`, generatedErrs[4])
+	assert.Equal(t, `on line 11, column 45: cannot assign field "stage" at index 17 through index chain [11]: unknown label "later", expected one of ["new" "done"]`, generatedErrs[5])
+
+	first := generated[0].(Order)
+	assert.Equal(t, int64(42), first.Customer.ID)
+	assert.Equal(t, "Ann", first.Customer.Name)
+	assert.Equal(t, "Paris", first.Customer.City)
+	assert.Equal(t, "ABC", first.Code.Value)
+	assert.Equal(t, []string{"a", "b"}, first.Tags)
+	require.NotNil(t, first.Shipped)
+	assert.Nil(t, generated[1].(Order).Shipped)
+}
+
+func TestUnmarshalCSVRecord_Pointer(t *testing.T) {
+	t.Parallel()
+
+	generated, generatedErrs := readAll(t, &Order{})
+	reflected, reflectedErrs := readAll(t, &reflectedOrder{})
+
+	require.Len(t, generated, len(reflected))
+	for i := range generated {
+		assert.Equal(t, (*Order)(reflected[i].(*reflectedOrder)), generated[i], "record %d", i)
+	}
+	assert.Equal(t, reflectedErrs, generatedErrs)
+}
+
+func TestUnmarshalCSVRecord_EnforceRequired(t *testing.T) {
+	t.Parallel()
+
+	// Generated methods don't check required fields, so Order is
+	// unmarshalled by reflection instead.
+	c := &commando.Config{Holder: Order{}, EnforceRequired: true}
+	um, err := c.NewUnmarshaller(newReader("order_id,id\n ,1\n"))
+	require.NoError(t, err)
+	_, err = um.Read()
+	assert.True(t, errors.Is(err, commando.ErrRequired), "%v", err)
+}
+
+func writeAll(t *testing.T, holder interface{}, records ...interface{}) string {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	m, err := commando.NewMarshaller(holder, w)
+	require.NoError(t, err)
+	for _, rec := range records {
+		require.NoError(t, m.Write(rec))
+	}
+	require.NoError(t, m.Flush())
+	return buf.String()
+}
+
+func TestMarshalCSVRecord(t *testing.T) {
+	t.Parallel()
+
+	shipped := time.Date(2020, 2, 3, 0, 0, 0, 0, time.UTC)
+	quantity := 3
+	orders := []Order{
+		{},
+		{
+			ID: 1,
+			Customer: Customer{
+				ID:      42,
+				Name:    "Ann",
+				Email:   "ann@example.com",
+				Address: &Address{Street: "Rue 1", City: "Paris"},
+				Audit:   Audit{CreatedBy: "bob", CreatedAt: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)},
+			},
+			Amount:   10.5,
+			Discount: 0.1,
+			Paid:     true,
+			Status:   "open",
+			Code:     Code{"ABC"},
+			Shipped:  &shipped,
+			Quantity: &quantity,
+			Tags:     []string{"a"},
+			Priority: -7,
+			Stage:    1,
+		},
+	}
+
+	var generated, reflected []interface{}
+	for i := range orders {
+		generated = append(generated, &orders[i])
+		reflected = append(reflected, (*reflectedOrder)(&orders[i]))
+	}
+
+	out := writeAll(t, &Order{}, generated...)
+	assert.Equal(t, writeAll(t, &reflectedOrder{}, reflected...), out)
+	assert.Contains(t, out, "1,42,Ann,ann@example.com,Rue 1,Paris,bob,2020-01-02T03:04:05Z,10.5,0.1,true,open,abc,2020-02-03T00:00:00Z,3,,-7,done\n")
+
+	m, err := commando.NewMarshaller(&Order{}, csv.NewWriter(ioutil.Discard))
+	require.NoError(t, err)
+	assert.EqualError(t, m.Write(&Order{Stage: 5}), `value "5" has no label`)
+	m, err = commando.NewMarshaller(&reflectedOrder{}, csv.NewWriter(ioutil.Discard))
+	require.NoError(t, err)
+	assert.EqualError(t, m.Write(&reflectedOrder{Stage: 5}), `value "5" has no label`)
+}
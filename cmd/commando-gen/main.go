@@ -0,0 +1,62 @@
+// Command commando-gen generates methods which encode and decode
+// structs as CSV rows without reflection.
+//
+// For each named struct type, it generates
+//
+//	func (x *T) UnmarshalCSVRecord(headers, row []string) error
+//	func (x *T) MarshalCSVRecord() ([]string, error)
+//
+// which commando's Unmarshaller and Marshaller use instead of
+// reflection.  The generated code follows the same csv tag rules,
+// and converts values the same way, as the reflection-based path.
+//
+// Usage, typically from a go:generate directive in the package
+// which defines the types:
+//
+//	//go:generate commando-gen -type=Payment,Refund
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("commando-gen: ")
+
+	typeNames := flag.String("type", "", "comma-separated list of struct type names; required")
+	output := flag.String("output", "", "output file name; default <type>_csv.go")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: commando-gen -type=T [-output=file] [directory]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *typeNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	types := strings.Split(*typeNames, ",")
+	if *output == "" {
+		*output = strings.ToLower(types[0]) + "_csv.go"
+	}
+	outputPath := filepath.Join(dir, *output)
+
+	src, err := generate(dir, types, outputPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(outputPath, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,284 @@
+package commando
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// --------------------------------------------------------------------------
+// Compiled codecs
+//
+// setField and getFieldAsString work out how to convert a value every
+// time they're called.  Since a field's type never changes, the same
+// decisions are made once per field by compileDecoder and
+// compileEncoder, and the resulting closures are cached on fieldInfo.
+// Both must behave exactly like the functions they replace; anything
+// they don't have a fast path for falls back to them.
+
+// decodeFunc sets field from its CSV representation.
+type decodeFunc func(field reflect.Value, value string) error
+
+// encodeFunc returns the CSV representation of field.
+type encodeFunc func(field reflect.Value) (string, error)
+
+var (
+	typeUnmarshallerType = reflect.TypeOf((*TypeUnmarshaller)(nil)).Elem()
+	textUnmarshalerType  = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	typeMarshallerType   = reflect.TypeOf((*TypeMarshaller)(nil)).Elem()
+	textMarshalerType    = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	stringerType         = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+	recordUnmarshallerType = reflect.TypeOf((*RecordUnmarshaller)(nil)).Elem()
+	afterUnmarshallerType  = reflect.TypeOf((*AfterUnmarshaller)(nil)).Elem()
+	validatorType          = reflect.TypeOf((*Validator)(nil)).Elem()
+	beforeMarshallerType   = reflect.TypeOf((*BeforeMarshaller)(nil)).Elem()
+)
+
+// isPredeclared reports whether t is one of Go's predeclared types,
+// such as int or string, as opposed to a type defined from one.
+func isPredeclared(t reflect.Type) bool {
+	return t.PkgPath() == "" && t.Name() != ""
+}
+
+// compileDecoder returns a decodeFunc for fields of type t, equivalent
+// to setField.
+func compileDecoder(t reflect.Type, omitEmpty bool) decodeFunc {
+	if t.Kind() == reflect.Ptr {
+		elemType := t.Elem()
+		if elemType.Kind() == reflect.Ptr || elemType.Kind() == reflect.Interface {
+			return genericDecoder(omitEmpty)
+		}
+		decodeElem := compileValueDecoder(elemType)
+		return func(field reflect.Value, value string) error {
+			if omitEmpty && value == "" {
+				return nil
+			}
+			if field.IsNil() {
+				field.Set(reflect.New(elemType))
+			}
+			return decodeElem(field.Elem(), value)
+		}
+	}
+	return compileValueDecoder(t)
+}
+
+func genericDecoder(omitEmpty bool) decodeFunc {
+	return func(field reflect.Value, value string) error {
+		return setField(field, value, omitEmpty)
+	}
+}
+
+// compileValueDecoder returns a decodeFunc for addressable,
+// non-pointer fields of type t.
+func compileValueDecoder(t reflect.Type) decodeFunc {
+	if isPredeclared(t) {
+		if decode := kindDecoder(t.Kind()); decode != nil {
+			return decode
+		}
+	}
+
+	ptrType := reflect.PtrTo(t)
+	switch {
+	case ptrType.Implements(typeUnmarshallerType):
+		return func(field reflect.Value, value string) error {
+			return field.Addr().Interface().(TypeUnmarshaller).UnmarshalCSV(value)
+		}
+	case ptrType.Implements(textUnmarshalerType):
+		return func(field reflect.Value, value string) error {
+			return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+		}
+	}
+
+	// Not unmarshallable, check for kind, e.g. renamed type from basic type
+	if decode := kindDecoder(t.Kind()); decode != nil {
+		return decode
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Struct:
+		return func(field reflect.Value, value string) error {
+			return json.Unmarshal([]byte(value), field.Addr().Interface())
+		}
+	}
+	return genericDecoder(false)
+}
+
+// kindDecoder returns a decodeFunc for the basic kind k, or nil if k
+// isn't basic.
+func kindDecoder(k reflect.Kind) decodeFunc {
+	switch k {
+	case reflect.String:
+		return func(field reflect.Value, value string) error {
+			field.SetString(value)
+			return nil
+		}
+	case reflect.Bool:
+		return func(field reflect.Value, value string) error {
+			b, err := ParseBool(value)
+			if err != nil {
+				return err
+			}
+			field.SetBool(b)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(field reflect.Value, value string) error {
+			i, err := ParseInt(value)
+			if err != nil {
+				return err
+			}
+			field.SetInt(i)
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return func(field reflect.Value, value string) error {
+			ui, err := ParseUint(value)
+			if err != nil {
+				return err
+			}
+			field.SetUint(ui)
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		return func(field reflect.Value, value string) error {
+			f, err := ParseFloat(value)
+			if err != nil {
+				return err
+			}
+			field.SetFloat(f)
+			return nil
+		}
+	}
+	return nil
+}
+
+// compileEncoder returns an encodeFunc for fields of type t,
+// equivalent to getFieldAsString.
+func compileEncoder(t reflect.Type) encodeFunc {
+	switch t.Kind() {
+	case reflect.Interface:
+		return getFieldAsString
+	case reflect.Ptr:
+		encodeElem := compileEncoder(t.Elem())
+		return func(field reflect.Value) (string, error) {
+			if field.IsNil() {
+				return "", nil
+			}
+			return encodeElem(field.Elem())
+		}
+	}
+
+	if isPredeclared(t) {
+		if encode := kindEncoder(t.Kind()); encode != nil {
+			return encode
+		}
+	}
+
+	// Methods on *T can only be used if the field is addressable,
+	// which depends on how the record was passed to the Marshaller.
+	encodePtr := methodEncoder(reflect.PtrTo(t))
+	encodeValue := methodEncoder(t)
+	encodeKind := kindEncoder(t.Kind())
+	if encodeKind == nil {
+		encodeKind = getFieldAsString
+	}
+	if encodePtr == nil && encodeValue == nil {
+		return encodeKind
+	}
+	return func(field reflect.Value) (string, error) {
+		if field.CanAddr() {
+			if encodePtr != nil {
+				return encodePtr(field.Addr())
+			}
+		} else if encodeValue != nil {
+			return encodeValue(field)
+		}
+		return encodeKind(field)
+	}
+}
+
+// methodEncoder returns an encodeFunc which uses the marshalling
+// method of values of type t, in the same order of preference as
+// marshal, or nil if t has no such method.
+func methodEncoder(t reflect.Type) encodeFunc {
+	switch {
+	case t.Implements(typeMarshallerType):
+		return func(field reflect.Value) (string, error) {
+			return field.Interface().(TypeMarshaller).MarshalCSV()
+		}
+	case t.Implements(textMarshalerType):
+		return func(field reflect.Value) (string, error) {
+			text, err := field.Interface().(encoding.TextMarshaler).MarshalText()
+			return string(text), err
+		}
+	case t.Implements(stringerType):
+		return func(field reflect.Value) (string, error) {
+			return field.Interface().(fmt.Stringer).String(), nil
+		}
+	}
+	return nil
+}
+
+// kindEncoder returns an encodeFunc for the basic kind k, or nil if k
+// isn't basic.
+func kindEncoder(k reflect.Kind) encodeFunc {
+	switch k {
+	case reflect.String:
+		return func(field reflect.Value) (string, error) {
+			return field.String(), nil
+		}
+	case reflect.Bool:
+		return func(field reflect.Value) (string, error) {
+			return strconv.FormatBool(field.Bool()), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(field reflect.Value) (string, error) {
+			return strconv.FormatInt(field.Int(), 10), nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return func(field reflect.Value) (string, error) {
+			return strconv.FormatUint(field.Uint(), 10), nil
+		}
+	case reflect.Float32:
+		return func(field reflect.Value) (string, error) {
+			return strconv.FormatFloat(field.Float(), 'f', -1, 32), nil
+		}
+	case reflect.Float64:
+		return func(field reflect.Value) (string, error) {
+			return strconv.FormatFloat(field.Float(), 'f', -1, 64), nil
+		}
+	}
+	return nil
+}
+
+// fieldForWrite returns the field of the struct v at index,
+// allocating any nil embedded struct pointers along the way.
+func fieldForWrite(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// fieldForRead returns the field of the struct v at index.  It
+// returns false if an embedded struct pointer along the way is nil.
+func fieldForRead(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
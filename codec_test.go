@@ -0,0 +1,246 @@
+package commando
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type codecSample struct {
+	String    string                    `csv:"string"`
+	Bool      bool                      `csv:"bool"`
+	Int       int                       `csv:"int"`
+	Int8      int8                      `csv:"int8"`
+	Uint      uint                      `csv:"uint"`
+	Float32   float32                   `csv:"float32"`
+	Float64   float64                   `csv:"float64"`
+	IntPtr    *int                      `csv:"int_ptr"`
+	OmitPtr   *string                   `csv:"omit_ptr,omitempty"`
+	Alias     stringAlias               `csv:"alias"`
+	Custom    customStringAlias         `csv:"custom"`
+	Renamed   RenamedFloat64Default     `csv:"renamed"`
+	Time      time.Time                 `csv:"time"`
+	TimePtr   *time.Time                `csv:"time_ptr"`
+	Typed     sampleTypeUnmarshaller    `csv:"typed"`
+	TypedPtr  *sampleTypeUnmarshaller   `csv:"typed_ptr"`
+	Text      sampleTextUnmarshaller    `csv:"text"`
+	Stringer  sampleStringer            `csv:"stringer"`
+	Slice     []int                     `csv:"slice"`
+	Iface     interface{}               `csv:"iface"`
+	Marshaled MarshalSample             `csv:"marshaled"`
+	Nested    map[string]sampleStringer `csv:"nested"`
+}
+
+var codecValues = []string{
+	"str", "yes", "42.9", "-3", "7.5", "1.25", "2.5", "9", "", "alias", "custom",
+	"3.75", "2021-01-02T03:04:05Z", "2021-01-02T03:04:05Z", "typed", "typed ptr", "text",
+	"stringer", "[1,2]", "", "marshaled", "",
+}
+
+func TestCompiledCodecs(t *testing.T) {
+	t.Parallel()
+
+	si, err := getStructInfo(reflect.TypeOf(codecSample{}))
+	require.NoError(t, err)
+	require.Len(t, si.Fields, len(codecValues))
+
+	var compiled, generic codecSample
+	compiledValue := reflect.ValueOf(&compiled).Elem()
+	genericValue := reflect.ValueOf(&generic).Elem()
+	for i, fi := range si.Fields {
+		if fi.getFirstKey() == "iface" || fi.getFirstKey() == "nested" {
+			// setField can't decode these either.
+			continue
+		}
+		compiledErr := fi.decode(fieldForWrite(compiledValue, fi.IndexChain), codecValues[i])
+		genericErr := setField(genericValue.FieldByIndex(fi.IndexChain), codecValues[i], fi.omitEmpty)
+		assert.Equal(t, genericErr, compiledErr, "decoding %s", fi.getFirstKey())
+	}
+	assert.Equal(t, generic, compiled)
+	assert.Nil(t, compiled.OmitPtr, "Expected omitempty to leave the pointer nil")
+
+	// Encode both addressable and unaddressable values, since that
+	// changes which marshalling methods can be used.
+	for _, v := range []reflect.Value{compiledValue, reflect.ValueOf(compiled)} {
+		for _, fi := range si.Fields {
+			field, ok := fieldForRead(v, fi.IndexChain)
+			require.True(t, ok)
+			compiledStr, compiledErr := fi.encode(field)
+			genericStr, genericErr := getFieldAsString(field)
+			assert.Equal(t, genericErr, compiledErr, "encoding %s", fi.getFirstKey())
+			assert.Equal(t, genericStr, compiledStr, "encoding %s", fi.getFirstKey())
+		}
+	}
+
+	var invalid codecSample
+	for _, key := range []string{"bool", "int", "uint", "float64", "slice"} {
+		for i, fi := range si.Fields {
+			if fi.getFirstKey() != key {
+				continue
+			}
+			err := fi.decode(fieldForWrite(reflect.ValueOf(&invalid).Elem(), fi.IndexChain), "nope")
+			assert.Error(t, err, "decoding %s from %q", key, codecValues[i])
+		}
+	}
+}
+
+func TestCompiledCodecs_EmbeddedPointers(t *testing.T) {
+	t.Parallel()
+
+	um, err := NewUnmarshaller(EmbedPtrSample{}, csv.NewReader(strings.NewReader("first,foo,BAR,garply\na,b,3,4.5\n")))
+	require.NoError(t, err)
+	out, err := um.Read()
+	require.NoError(t, err)
+
+	rec := out.(EmbedPtrSample)
+	require.NotNil(t, rec.Sample, "Expected the embedded pointer to be allocated")
+	assert.Equal(t, "b", rec.Sample.Foo)
+	assert.Equal(t, 3, rec.Sample.Bar)
+
+	buf := new(bytes.Buffer)
+	m, err := NewMarshaller(EmbedPtrSample{}, csv.NewWriter(buf))
+	require.NoError(t, err)
+	require.NoError(t, m.Write(EmbedPtrSample{Qux: "a", Grault: 1}))
+	require.NoError(t, m.Flush())
+	assert.Equal(t, "first,foo,BAR,Baz,Quux,Blah,SPtr,Omit,garply,last\na,,,,,,,,1,\n", buf.String())
+}
+
+// --------------------------------------------------------------------------
+// Benchmarks
+//
+// The Reflect benchmarks decode and encode through setField and
+// getFieldAsString, as every cell did before codecs were compiled;
+// the Compiled ones use the codecs cached on fieldInfo.
+
+func benchFields(b *testing.B) ([]fieldInfo, []string) {
+	si, err := getStructInfo(reflect.TypeOf(codecSample{}))
+	if err != nil {
+		b.Fatal(err)
+	}
+	var fields []fieldInfo
+	var values []string
+	for i, value := range codecValues {
+		fi := si.Fields[i]
+		if fi.getFirstKey() == "iface" || fi.getFirstKey() == "nested" {
+			continue
+		}
+		fields = append(fields, fi)
+		values = append(values, value)
+	}
+	return fields, values
+}
+
+func BenchmarkDecodeRow_Reflect(b *testing.B) {
+	fields, values := benchFields(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		v := reflect.New(reflect.TypeOf(codecSample{})).Elem()
+		for i, fi := range fields {
+			if err := setField(v.FieldByIndex(fi.IndexChain), values[i], fi.omitEmpty); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkDecodeRow_Compiled(b *testing.B) {
+	fields, values := benchFields(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		v := reflect.New(reflect.TypeOf(codecSample{})).Elem()
+		for i, fi := range fields {
+			if err := fi.decode(fieldForWrite(v, fi.IndexChain), values[i]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func benchRecord(b *testing.B, fields []fieldInfo, values []string) reflect.Value {
+	v := reflect.New(reflect.TypeOf(codecSample{})).Elem()
+	for i, fi := range fields {
+		if err := fi.decode(fieldForWrite(v, fi.IndexChain), values[i]); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return v
+}
+
+func BenchmarkEncodeRow_Reflect(b *testing.B) {
+	fields, values := benchFields(b)
+	v := benchRecord(b, fields, values)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, fi := range fields {
+			if _, err := getFieldAsString(v.FieldByIndex(fi.IndexChain)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkEncodeRow_Compiled(b *testing.B) {
+	fields, values := benchFields(b)
+	v := benchRecord(b, fields, values)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, fi := range fields {
+			field, _ := fieldForRead(v, fi.IndexChain)
+			if _, err := fi.encode(field); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// repeatReader returns the same row forever.
+type repeatReader struct {
+	row []string
+}
+
+func (r *repeatReader) Read() ([]string, error) {
+	return r.row, nil
+}
+
+func BenchmarkUnmarshaller_Read(b *testing.B) {
+	um, err := NewUnmarshaller(Sample{}, csv.NewReader(strings.NewReader("foo,BAR,Baz,Quux,Blah,SPtr,Omit\n")))
+	if err != nil {
+		b.Fatal(err)
+	}
+	um.reader = &repeatReader{[]string{"foo", "42", "baz", "1.5", "7", "sptr", ""}}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := um.Read(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshaller_Write(b *testing.B) {
+	blah := 7
+	sptr := "sptr"
+	rec := &Sample{Foo: "foo", Bar: 42, Baz: "baz", Frop: 1.5, Blah: &blah, SPtr: &sptr}
+	m, err := NewMarshaller(&Sample{}, csv.NewWriter(ioutil.Discard))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if err := m.Write(rec); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -3,13 +3,15 @@ package commando
 import (
 	"fmt"
 	"reflect"
+	"strings"
 )
 
 type Config struct {
 	// Holder is the type of struct to marshal from/unmarshal info.
 	Holder interface{}
 
-	// ErrorHandler is invoked if there's a recoverable error.
+	// ErrorHandler is invoked if there's a recoverable error, by
+	// ReadAll and ReadAllCallback when they're given no onError func.
 	//
 	// If the func returns an error, processing stops.  If it returns
 	// nil, processing continues.
@@ -17,6 +19,31 @@ type Config struct {
 	// If unset, processing stops on the first error.
 	ErrorHandler func(error) error
 
+	// OnError, if set, is used instead of ErrorHandler, and is given
+	// the context.  Helpers such as MaxErrors can be set here, and
+	// keep a separate budget for each import.
+	OnError ErrorFunc
+
+	// WarningHandler, if set, is invoked for problems which don't
+	// stop a file being read, such as a column which no field is
+	// unmarshalled from.  With several Workers, it may be called
+	// from several goroutines at once.
+	WarningHandler func(Warning)
+
+	// Observer, if set, is told about each header, row, write and
+	// flush.  If it's nil, nothing is measured.
+	Observer Observer
+
+	// RaggedRows is how rows with more or fewer columns than the
+	// headers are handled.  By default, missing columns are left
+	// empty and extra columns are ignored.
+	RaggedRows RaggedRows
+
+	// EnforceRequired indicates whether fields tagged required must
+	// have a column in the file, and a value in every row.  If it's
+	// false, required only describes fields, for DescribeSchema.
+	EnforceRequired bool
+
 	// FailIfUnmatchedStructTags indicates whether it is considered an
 	// error when there is an unmatched struct tag.
 	FailIfUnmatchedStructTags bool
@@ -30,6 +57,48 @@ type Config struct {
 	// alignment in the struct definition.
 	ShouldAlignDuplicateHeadersWithStructFieldOrder bool
 
+	// Workers is the number of goroutines ReadAllCallback uses to
+	// decode rows.  One more goroutine reads rows from the Reader.
+	// If Workers is less than 2, rows are read and decoded on the
+	// calling goroutine.
+	Workers int
+
+	// Unordered indicates whether ReadAllCallback may deliver records
+	// in the order they finish decoding, rather than the order they
+	// were read, when decoding with multiple Workers.
+	Unordered bool
+
+	// HeaderAliases maps headers in files to the keys they stand for,
+	// such as struct tag keys or Schema column names, so a file's
+	// headers can be renamed without changing the struct.  Headers
+	// which aren't in the map are used as they are.
+	//
+	// Marshallers write the alias of each key instead of the key.  If
+	// a key has several aliases, the first in sorted order is used.
+	HeaderAliases map[string]string
+
+	// Variants maps the values of a discriminator column to the types
+	// of rows in files which interleave several types of rows.  If
+	// it's set, Holder is ignored, the file has no header row, and
+	// each row is unmarshalled into its variant's Holder.  A
+	// Marshaller accepts the Holder of any variant.
+	Variants map[string]Variant
+
+	// DiscriminatorIndex is the index of the column which identifies
+	// the variant of each row.
+	DiscriminatorIndex int
+
+	// Schema describes the columns of Record and map holders.  If
+	// it's set, Holder may be nil, in which case Records are read.
+	Schema *Schema
+
+	// Headers are the columns written by a Marshaller whose Holder
+	// is a Record, *Record or map[string]string, which have no struct
+	// tags to take them from.  Values are written in this order,
+	// whatever the order of a Record's own Headers.  With a Schema,
+	// its columns are written instead.
+	Headers []string
+
 	// idName indicates the column name of the ID of each row if the
 	// ID would like to be included in the unmarshalRow error message
 	idName string
@@ -38,20 +107,29 @@ type Config struct {
 // validate ensures that a struct was used to create the Unmarshaller, and validates
 // CSV headers against the CSV tags in the struct.
 func (c *Config) validate(headers []string) (*validConfig, error) {
-	concreteType := reflect.TypeOf(c.Holder)
-	if concreteType.Kind() == reflect.Ptr {
-		concreteType = concreteType.Elem()
+	if len(c.Variants) > 0 {
+		return c.validateVariants()
+	}
+	if c.Schema != nil {
+		return c.validateSchema(headers)
+	}
+	if isDynamic(reflect.TypeOf(c.Holder)) {
+		return c.validateDynamic(headers)
 	}
-	if err := ensureOutInnerType(concreteType); err != nil {
+
+	structInfo, err := getHolderStructInfo(c.Holder)
+	if err != nil {
 		return nil, err
 	}
-	structInfo := getStructInfo(concreteType) // Get struct info to get CSV annotations.
 	if len(structInfo.Fields) == 0 {
 		return nil, ErrNoStructTags
 	}
 	csvHeadersLabels := make([]*fieldInfo, len(headers)) // Used to store the corresponding header <-> position in CSV
 	headerCount := map[string]int{}
-	for i, csvColumnHeader := range headers {
+	// Match headers by their canonical keys, but keep the file's
+	// headers for error messages.
+	keys := c.headerKeys(headers)
+	for i, csvColumnHeader := range keys {
 		curHeaderCount := headerCount[csvColumnHeader]
 		if fieldInfo := getCSVFieldPosition(csvColumnHeader, structInfo, curHeaderCount); fieldInfo != nil {
 			csvHeadersLabels[i] = fieldInfo
@@ -62,35 +140,104 @@ func (c *Config) validate(headers []string) (*validConfig, error) {
 		}
 	}
 
-	mismatchedHeaders := mismatchHeaderFields(structInfo.Fields, headers)
-	mismatchedStructFields := mismatchStructFields(structInfo.Fields, headers)
+	mismatchedHeaders := mismatchHeaderFields(structInfo.Fields, keys)
+	mismatchedStructFields := mismatchStructFields(structInfo.Fields, keys)
 
 	// If none of the headers match the struct, return an error.
 	if len(headers) > 0 && len(mismatchedHeaders) == len(headers) {
-		return nil, fmt.Errorf("expected one or more of headers %v, but got %v ", structInfo.headers(), mismatchedHeaders)
+		return nil, newHeaderError(fmt.Sprintf("expected one or more of headers %v, but got %v ", structInfo.headers(), mismatchedHeaders),
+			mismatchedHeaders, structInfo.headers())
+	}
+
+	if c.EnforceRequired && len(headers) > 0 {
+		if err := missingRequiredFields(structInfo.Fields, keys); err != nil {
+			return nil, err
+		}
 	}
 
 	if c.FailIfUnmatchedStructTags {
 		if len(mismatchedStructFields) != 0 {
-			return nil, fmt.Errorf("found unmatched struct field with tags %v", mismatchedStructFields)
+			// Suggest the unmatched headers which resemble
+			// unmatched keys.
+			return nil, newHeaderError(fmt.Sprintf("found unmatched struct field with tags %v", mismatchedStructFields),
+				mismatchedHeaders, mismatchedStructFields)
 		}
 	}
 
 	if c.FailIfDoubleHeaderNames {
-		if err := maybeDoubleHeaderNames(headers); err != nil {
+		if err := maybeDoubleHeaderNames(keys); err != nil {
 			return nil, err
 		}
 	}
 
+	outType := reflect.TypeOf(c.Holder)
+	structType := outType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+
 	return &validConfig{
 		Config:       *c,
-		outType:      reflect.TypeOf(c.Holder),
+		outType:      outType,
 		headers:      headers,
 		structInfo:   structInfo,
 		fieldInfoMap: csvHeadersLabels,
+		// Generated unmarshallers map headers themselves, so they
+		// can't honor options which change the mapping or the
+		// error messages.
+		recordUnmarshaller: reflect.PtrTo(structType).Implements(recordUnmarshallerType) &&
+			!c.ShouldAlignDuplicateHeadersWithStructFieldOrder && c.idName == "" && len(c.HeaderAliases) == 0 &&
+			!c.EnforceRequired,
+		hasRules:          hasRules(structInfo.Fields),
+		afterUnmarshaller: reflect.PtrTo(structType).Implements(afterUnmarshallerType),
+		validator:         reflect.PtrTo(structType).Implements(validatorType),
+		beforeMarshaller:  reflect.PtrTo(structType).Implements(beforeMarshallerType),
 	}, nil
 }
 
+// headerKeys returns the keys which headers stand for, according to
+// HeaderAliases.  Like struct tags, aliases match headers with
+// surrounding whitespace.
+func (c *Config) headerKeys(headers []string) []string {
+	if len(c.HeaderAliases) == 0 {
+		return headers
+	}
+	keys := make([]string, len(headers))
+	for i, header := range headers {
+		if key, ok := c.HeaderAliases[header]; ok {
+			keys[i] = key
+		} else if key, ok := c.HeaderAliases[strings.TrimSpace(header)]; ok {
+			keys[i] = key
+		} else {
+			keys[i] = header
+		}
+	}
+	return keys
+}
+
+// aliasHeaders returns the headers to write for keys, according to
+// HeaderAliases.
+func (c *Config) aliasHeaders(keys []string) []string {
+	if len(c.HeaderAliases) == 0 {
+		return keys
+	}
+	aliases := make(map[string]string, len(c.HeaderAliases))
+	for header, key := range c.HeaderAliases {
+		if alias, ok := aliases[key]; !ok || header < alias {
+			aliases[key] = header
+		}
+	}
+	headers := make([]string, len(keys))
+	for i, key := range keys {
+		if alias, ok := aliases[key]; ok {
+			headers[i] = alias
+		} else {
+			headers[i] = key
+		}
+	}
+	return headers
+}
+
 // validConfig is a Config which has been validated and contains
 // metadata about the output struct type.
 type validConfig struct {
@@ -103,4 +250,30 @@ type validConfig struct {
 
 	structInfo   *structInfo
 	fieldInfoMap []*fieldInfo
+
+	// recordUnmarshaller indicates whether rows should be decoded
+	// by the holder's RecordUnmarshaller implementation.
+	recordUnmarshaller bool
+
+	// hasRules indicates whether any field has validation rules.
+	hasRules bool
+
+	// afterUnmarshaller, validator and beforeMarshaller indicate
+	// whether the holder implements those interfaces, through a
+	// pointer.
+	afterUnmarshaller bool
+	validator         bool
+	beforeMarshaller  bool
+
+	// dynamic indicates whether the Holder is a Record or map
+	// rather than a struct.
+	dynamic bool
+
+	// schema is the Schema matched against the headers, if any.
+	schema *schemaLayout
+
+	// variants are the validated Variants, by discriminator value,
+	// and variantKeys are their discriminator values, by holder type.
+	variants    map[string]*validConfig
+	variantKeys map[reflect.Type]string
 }
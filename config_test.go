@@ -1,7 +1,13 @@
 package commando
 
 import (
+	"bytes"
+	"encoding/csv"
+	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_Config_Validate(t *testing.T) {
@@ -20,3 +26,56 @@ func Test_Config_Validate(t *testing.T) {
 		t.Fatal("Expected an error.")
 	}
 }
+
+func Test_Config_HeaderAliases(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{
+		Holder: sample{},
+		HeaderAliases: map[string]string{
+			"Column A": "field_a",
+			"col_a":    "field_a",
+			"Column B": "field_b",
+		},
+	}
+	um, err := c.NewUnmarshaller(csv.NewReader(strings.NewReader("Column A, Column B\na,b\n")))
+	require.NoError(t, err)
+	out, err := um.Read()
+	require.NoError(t, err)
+	assert.Equal(t, sample{FieldA: "a", FieldB: "b"}, out)
+
+	um, err = c.NewUnmarshaller(csv.NewReader(strings.NewReader("col_a,field_b\na,x\n")))
+	require.NoError(t, err)
+	out, err = um.Read()
+	require.NoError(t, err)
+	assert.Equal(t, sample{FieldA: "a", FieldB: "x"}, out)
+
+	c.FailIfDoubleHeaderNames = true
+	_, err = c.NewUnmarshaller(csv.NewReader(strings.NewReader("col_a,field_a\n")))
+	assert.EqualError(t, err, "repeated header name: field_a")
+
+	buf := new(bytes.Buffer)
+	m, err := c.NewMarshaller(csv.NewWriter(buf))
+	require.NoError(t, err)
+	require.NoError(t, m.Write(sample{FieldA: "a", FieldB: "b"}))
+	require.NoError(t, m.Flush())
+	assert.Equal(t, "Column A,Column B\na,b\n", buf.String())
+}
+
+func Test_Config_HeaderAliases_Record(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{Holder: Record{}, HeaderAliases: map[string]string{"Qty": "qty"}}
+	um, err := c.NewUnmarshaller(csv.NewReader(strings.NewReader("Qty,sku\n3,a\n")))
+	require.NoError(t, err)
+	out, err := um.Read()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"qty", "sku"}, out.(Record).Headers)
+
+	c = &Config{Schema: testSchema, HeaderAliases: map[string]string{"SKU": "sku"}}
+	um, err = c.NewUnmarshaller(csv.NewReader(strings.NewReader("SKU\nabc\n")))
+	require.NoError(t, err)
+	out, err = um.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "abc", out.(Record).Value("sku"))
+}
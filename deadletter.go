@@ -0,0 +1,63 @@
+package commando
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// DeadLetter returns an error handler for ReadAllCallback which
+// writes each row which couldn't be unmarshalled to w, and carries on.
+// The rows are written under um's headers, followed by a _line column
+// with the line the row started on, an _error column with the error,
+// and an _extra column with any cells beyond the headers, written as
+// a line of CSV.  Every row has the same number of columns, so short
+// rows are padded.  Once the mistakes are fixed, the rows can be read
+// again with the same Config, which ignores the extra columns.
+//
+// Errors which aren't about a row, such as the Reader failing to
+// parse the file, stop processing, as do errors writing to w.  w
+// isn't flushed.
+func (um *Unmarshaller) DeadLetter(w Writer) func(context.Context, error) error {
+	wroteHeaders := false
+	return func(_ context.Context, err error) error {
+		var pe *PositionError
+		if !errors.As(err, &pe) {
+			return err
+		}
+
+		headers := um.config.fileHeaders()
+		if !wroteHeaders && len(headers) > 0 {
+			if err := w.Write(append(append([]string(nil), headers...), "_line", "_error", "_extra")); err != nil {
+				return err
+			}
+		}
+		wroteHeaders = true
+
+		// Move the cells of long rows beyond the headers to _extra,
+		// and pad short ones, so that _line, _error and _extra line
+		// up with their headers.
+		record := append([]string(nil), pe.Row...)
+		var extra string
+		if len(headers) > 0 && len(record) > len(headers) {
+			extra = joinCells(record[len(headers):])
+			record = record[:len(headers)]
+		}
+		for len(record) < len(headers) {
+			record = append(record, "")
+		}
+		return w.Write(append(record, strconv.Itoa(pe.StartLine), err.Error(), extra))
+	}
+}
+
+// joinCells returns cells as a line of CSV, without the newline.
+func joinCells(cells []string) string {
+	b := new(strings.Builder)
+	cw := csv.NewWriter(b)
+	// Writing to a strings.Builder can't fail.
+	_ = cw.Write(cells)
+	cw.Flush()
+	return strings.TrimSuffix(b.String(), "\n")
+}
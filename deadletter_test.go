@@ -0,0 +1,85 @@
+package commando
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshaller_DeadLetter(t *testing.T) {
+	t.Parallel()
+
+	reader := csv.NewReader(strings.NewReader("name,age\nann,1\n\"bo\nb\",x\ncy\ndee,4\n"))
+	reader.FieldsPerRecord = -1
+	um, err := NewUnmarshaller(metaStruct{}, reader)
+	require.NoError(t, err)
+
+	rejects := new(bytes.Buffer)
+	w := csv.NewWriter(rejects)
+	out, err := um.ReadAll(context.Background(), um.DeadLetter(w))
+	require.NoError(t, err)
+	w.Flush()
+	assert.Equal(t, []metaStruct{{Name: "ann", Age: 1}, {Name: "cy"}, {Name: "dee", Age: 4}}, out)
+	assert.Equal(t, `name,age,_line,_error,_extra
+"bo
+b",x,3,"on line 4, column 4: cannot assign field ""age"" at index 1 through index chain [1]: strconv.ParseInt: parsing ""x"": invalid syntax",
+`, rejects.String())
+
+	// The fixed rejects can be read with the same holder.
+	um, err = NewUnmarshaller(metaStruct{}, csv.NewReader(strings.NewReader(strings.Replace(rejects.String(), ",x,", ",2,", 1))))
+	require.NoError(t, err)
+	out, err = um.ReadAll(context.Background(), StopOnError)
+	require.NoError(t, err)
+	assert.Equal(t, []metaStruct{{Name: "bo\nb", Age: 2}}, out)
+}
+
+func TestUnmarshaller_DeadLetterRagged(t *testing.T) {
+	t.Parallel()
+
+	um, err := (&Config{Holder: raggedStruct{}, RaggedRows: RaggedError}).NewUnmarshaller(raggedRows())
+	require.NoError(t, err)
+
+	rejects := new(bytes.Buffer)
+	w := csv.NewWriter(rejects)
+	_, err = um.ReadAll(context.Background(), um.DeadLetter(w))
+	require.NoError(t, err)
+	w.Flush()
+	assert.Equal(t, `foo,bar,_line,_error,_extra
+b,,3,"on line 3: wrong number of columns: row has 1, expected 2",
+c,3,4,"on line 4: wrong number of columns: row has 3, expected 2",extra
+`, rejects.String())
+
+	// Every row has as many columns as the headers, so the rejects
+	// can be read by a csv.Reader which checks.
+	records, err := csv.NewReader(strings.NewReader(rejects.String())).ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, records, 3)
+}
+
+func TestUnmarshaller_DeadLetterExtra(t *testing.T) {
+	t.Parallel()
+
+	// Extra cells are kept, even if they have commas or quotes.
+	reader := csv.NewReader(strings.NewReader("name,age\nann,x,\"a,b\",\"say \"\"hi\"\"\"\n"))
+	reader.FieldsPerRecord = -1
+	um, err := (&Config{Holder: metaStruct{}, RaggedRows: RaggedError}).NewUnmarshaller(reader)
+	require.NoError(t, err)
+
+	rejects := new(bytes.Buffer)
+	w := csv.NewWriter(rejects)
+	_, err = um.ReadAll(context.Background(), um.DeadLetter(w))
+	require.NoError(t, err)
+	w.Flush()
+
+	records, err := csv.NewReader(strings.NewReader(rejects.String())).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	extra, err := csv.NewReader(strings.NewReader(records[1][4])).Read()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a,b", `say "hi"`}, extra)
+}
@@ -8,6 +8,10 @@ import (
 
 var (
 	ErrNoStructTags = errors.New("no csv struct tags found")
+
+	// ErrRequired is the error for an empty value in a required
+	// column, with Config.EnforceRequired or a Schema.
+	ErrRequired = errors.New("value is required")
 )
 
 func mismatchStructFields(structInfo []fieldInfo, headers []string) []string {
@@ -57,6 +61,27 @@ func mismatchHeaderFields(structInfo []fieldInfo, headers []string) []string {
 	return missing
 }
 
+// missingRequiredFields returns an error if a required field has no
+// header.
+func missingRequiredFields(fields []fieldInfo, headers []string) error {
+	for _, field := range fields {
+		if !field.required {
+			continue
+		}
+		found := false
+		for _, header := range headers {
+			if field.matchesKey(header) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("missing required column %q", field.getFirstKey())
+		}
+	}
+	return nil
+}
+
 // Check that no header name is repeated twice
 func maybeDoubleHeaderNames(headers []string) error {
 	headerMap := make(map[string]bool, len(headers))
@@ -97,20 +122,3 @@ func createNewOutInner(outInnerWasPointer bool, outInnerType reflect.Type) refle
 	}
 	return reflect.New(outInnerType).Elem()
 }
-
-func setInnerField(outInner *reflect.Value, outInnerWasPointer bool, index []int, value string, omitEmpty bool) error {
-	oi := *outInner
-	if outInnerWasPointer {
-		// initialize nil pointer
-		if oi.IsNil() {
-			setField(oi, "", omitEmpty)
-		}
-		oi = outInner.Elem()
-	}
-	// because pointers can be nil need to recurse one index at a time and perform nil check
-	if len(index) > 1 {
-		nextField := oi.Field(index[0])
-		return setInnerField(&nextField, nextField.Kind() == reflect.Ptr, index[1:], value, omitEmpty)
-	}
-	return setField(oi.FieldByIndex(index), value, omitEmpty)
-}
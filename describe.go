@@ -0,0 +1,546 @@
+package commando
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// DescribeSchema returns the Schema of the columns of holder, a struct
+// or a pointer to one, as described by its csv tags.  Columns are
+// named by their first tag key, and their other keys are aliases.
+// Fields with an enum option are string columns of their labels.
+func DescribeSchema(holder interface{}) (*Schema, error) {
+	if isDynamic(reflect.TypeOf(holder)) {
+		return nil, fmt.Errorf("cannot describe %T, only structs have a schema", holder)
+	}
+	structInfo, err := getHolderStructInfo(holder)
+	if err != nil {
+		return nil, err
+	}
+	if len(structInfo.Fields) == 0 {
+		return nil, ErrNoStructTags
+	}
+
+	structType := reflect.TypeOf(holder)
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	schema := &Schema{Columns: make([]Column, len(structInfo.Fields))}
+	for i, field := range structInfo.Fields {
+		column := Column{
+			Name:     field.getFirstKey(),
+			Aliases:  append([]string(nil), field.keys[1:]...),
+			Required: field.required,
+		}
+		if field.enum != nil {
+			column.Type = TypeString
+			column.Enum = append([]string(nil), field.enum.labels...)
+		} else {
+			column.Type = describeType(structType.FieldByIndex(field.IndexChain).Type)
+		}
+		schema.Columns[i] = column
+	}
+	return schema, nil
+}
+
+// describeType returns the ColumnType of fields of type t, following
+// the same rules as compileDecoder.
+func describeType(t reflect.Type) ColumnType {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return TypeDateTime
+	}
+
+	ptrType := reflect.PtrTo(t)
+	if !isPredeclared(t) && (ptrType.Implements(typeUnmarshallerType) || ptrType.Implements(textUnmarshalerType)) {
+		return TypeString
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return TypeBoolean
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return TypeInteger
+	case reflect.Float32, reflect.Float64:
+		return TypeNumber
+	case reflect.Slice:
+		return TypeArray
+	case reflect.Struct:
+		return TypeObject
+	}
+	return TypeString
+}
+
+// defaultFormat returns the layout of c's values if it has no Format.
+func (c Column) defaultFormat() string {
+	switch c.Type {
+	case TypeDate:
+		return DefaultDateFormat
+	case TypeDateTime:
+		return DefaultDateTimeFormat
+	}
+	return ""
+}
+
+// hasFormat reports whether c has a Format other than its default.
+func (c Column) hasFormat() bool {
+	return c.Format != "" && c.Format != c.defaultFormat()
+}
+
+// --------------------------------------------------------------------------
+// Frictionless Table Schema
+//
+// https://specs.frictionlessdata.io/table-schema/
+
+type tableSchema struct {
+	Fields []tableSchemaField `json:"fields"`
+}
+
+type tableSchemaField struct {
+	Name        string                 `json:"name"`
+	Type        string                 `json:"type,omitempty"`
+	Format      string                 `json:"format,omitempty"`
+	Constraints *tableSchemaConstraint `json:"constraints,omitempty"`
+}
+
+type tableSchemaConstraint struct {
+	Required bool `json:"required,omitempty"`
+
+	// Enum values may be any JSON value, such as numbers.
+	Enum []json.RawMessage `json:"enum,omitempty"`
+}
+
+// MarshalTableSchema returns s as a Frictionless Table Schema.  Table
+// Schema has no aliases or defaults, so they're left out.
+func (s *Schema) MarshalTableSchema() ([]byte, error) {
+	ts := tableSchema{Fields: make([]tableSchemaField, len(s.Columns))}
+	for i, column := range s.Columns {
+		field := tableSchemaField{Name: column.Name, Type: string(column.Type)}
+		if field.Type == "" {
+			field.Type = string(TypeString)
+		}
+		if column.hasFormat() {
+			format, err := layoutToStrftime(column.Format)
+			if err != nil {
+				return nil, fmt.Errorf("column %q: %v", column.Name, err)
+			}
+			field.Format = format
+		}
+		if column.Required || len(column.Enum) > 0 {
+			field.Constraints = &tableSchemaConstraint{Required: column.Required}
+			for _, v := range column.Enum {
+				raw, _ := json.Marshal(v)
+				field.Constraints.Enum = append(field.Constraints.Enum, raw)
+			}
+		}
+		ts.Fields[i] = field
+	}
+	return json.MarshalIndent(ts, "", "  ")
+}
+
+// ParseTableSchema parses a Frictionless Table Schema.
+func ParseTableSchema(data []byte) (*Schema, error) {
+	var ts tableSchema
+	if err := json.Unmarshal(data, &ts); err != nil {
+		return nil, err
+	}
+
+	schema := &Schema{Columns: make([]Column, len(ts.Fields))}
+	for i, field := range ts.Fields {
+		column := Column{Name: field.Name}
+		if field.Constraints != nil {
+			column.Required = field.Constraints.Required
+			for _, raw := range field.Constraints.Enum {
+				// Values other than strings are kept as they're
+				// written, such as 1 or true.
+				var v string
+				if err := json.Unmarshal(raw, &v); err != nil {
+					v = string(raw)
+				}
+				column.Enum = append(column.Enum, v)
+			}
+		}
+		switch field.Type {
+		case "", "any":
+			column.Type = TypeString
+		case "string", "integer", "number", "boolean", "date", "datetime", "array", "object":
+			column.Type = ColumnType(field.Type)
+		default:
+			return nil, fmt.Errorf("field %q: unsupported type %q", field.Name, field.Type)
+		}
+		// Formats of other types, such as "email", aren't checked.
+		if (column.Type == TypeDate || column.Type == TypeDateTime) && field.Format != "" && field.Format != "default" {
+			layout, err := strftimeToLayout(field.Format)
+			if err != nil {
+				return nil, fmt.Errorf("field %q: %v", field.Name, err)
+			}
+			column.Format = layout
+		}
+		schema.Columns[i] = column
+	}
+	return schema, nil
+}
+
+// --------------------------------------------------------------------------
+// W3C CSV on the Web metadata
+//
+// https://www.w3.org/TR/tabular-metadata/
+
+const csvwContext = "http://www.w3.org/ns/csvw"
+
+type csvwTable struct {
+	Context     interface{} `json:"@context"`
+	URL         string      `json:"url,omitempty"`
+	TableSchema *csvwSchema `json:"tableSchema"`
+}
+
+type csvwSchema struct {
+	Columns []csvwColumn `json:"columns"`
+}
+
+type csvwColumn struct {
+	Name     string          `json:"name,omitempty"`
+	Titles   json.RawMessage `json:"titles,omitempty"`
+	Datatype json.RawMessage `json:"datatype,omitempty"`
+	Required bool            `json:"required,omitempty"`
+	Default  string          `json:"default,omitempty"`
+}
+
+type csvwDatatype struct {
+	Base   string `json:"base"`
+	Format string `json:"format,omitempty"`
+}
+
+var csvwDatatypes = map[ColumnType]string{
+	TypeString:   "string",
+	TypeInteger:  "integer",
+	TypeNumber:   "number",
+	TypeBoolean:  "boolean",
+	TypeDate:     "date",
+	TypeDateTime: "dateTime",
+	TypeArray:    "json",
+	TypeObject:   "json",
+}
+
+// MarshalCSVW returns s as the metadata of a CSV on the Web table
+// at url.  A column's name and aliases are its titles.  CSVW has no
+// enums, so they're left out.
+func (s *Schema) MarshalCSVW(url string) ([]byte, error) {
+	table := csvwTable{
+		Context:     csvwContext,
+		URL:         url,
+		TableSchema: &csvwSchema{Columns: make([]csvwColumn, len(s.Columns))},
+	}
+	for i, column := range s.Columns {
+		c := csvwColumn{Name: column.Name, Required: column.Required, Default: column.Default}
+		if len(column.Aliases) > 0 {
+			c.Titles, _ = json.Marshal(append([]string{column.Name}, column.Aliases...))
+		}
+
+		columnType := column.Type
+		if columnType == "" {
+			columnType = TypeString
+		}
+		datatype := csvwDatatype{Base: csvwDatatypes[columnType]}
+		if column.hasFormat() {
+			format, err := layoutToUTS35(column.Format)
+			if err != nil {
+				return nil, fmt.Errorf("column %q: %v", column.Name, err)
+			}
+			datatype.Format = format
+		}
+		if datatype.Format == "" {
+			c.Datatype, _ = json.Marshal(datatype.Base)
+		} else {
+			c.Datatype, _ = json.Marshal(datatype)
+		}
+		table.TableSchema.Columns[i] = c
+	}
+	return json.MarshalIndent(table, "", "  ")
+}
+
+// ParseCSVW parses the metadata of a CSV on the Web table.  The json
+// datatype has no equivalent ColumnType, so such columns are strings.
+func ParseCSVW(data []byte) (*Schema, error) {
+	var table csvwTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, err
+	}
+	if table.TableSchema == nil {
+		return nil, fmt.Errorf("no tableSchema")
+	}
+
+	schema := &Schema{Columns: make([]Column, len(table.TableSchema.Columns))}
+	for i, c := range table.TableSchema.Columns {
+		titles, err := parseCSVWTitles(c.Titles)
+		if err != nil {
+			return nil, fmt.Errorf("column %d: titles: %v", i, err)
+		}
+		column := Column{Name: c.Name, Required: c.Required, Default: c.Default}
+		if column.Name == "" {
+			if len(titles) == 0 {
+				return nil, fmt.Errorf("column %d has no name or titles", i)
+			}
+			column.Name = titles[0]
+		}
+		for _, title := range titles {
+			if title != column.Name {
+				column.Aliases = append(column.Aliases, title)
+			}
+		}
+
+		datatype := csvwDatatype{Base: "string"}
+		if len(c.Datatype) > 0 {
+			if err := json.Unmarshal(c.Datatype, &datatype.Base); err != nil {
+				if err := json.Unmarshal(c.Datatype, &datatype); err != nil {
+					return nil, fmt.Errorf("column %q: datatype: %v", column.Name, err)
+				}
+			}
+		}
+		switch datatype.Base {
+		case "string", "normalizedString", "token", "anyURI", "json", "":
+			column.Type = TypeString
+		case "integer", "int", "long", "short", "byte",
+			"nonNegativeInteger", "positiveInteger", "nonPositiveInteger", "negativeInteger",
+			"unsignedLong", "unsignedInt", "unsignedShort", "unsignedByte":
+			column.Type = TypeInteger
+		case "number", "double", "float", "decimal":
+			column.Type = TypeNumber
+		case "boolean":
+			column.Type = TypeBoolean
+		case "date":
+			column.Type = TypeDate
+		case "dateTime", "datetime", "dateTimeStamp":
+			column.Type = TypeDateTime
+		default:
+			return nil, fmt.Errorf("column %q: unsupported datatype %q", column.Name, datatype.Base)
+		}
+		// Formats of other types, such as "yes|no", aren't checked.
+		if (column.Type == TypeDate || column.Type == TypeDateTime) && datatype.Format != "" {
+			layout, err := uts35ToLayout(datatype.Format)
+			if err != nil {
+				return nil, fmt.Errorf("column %q: %v", column.Name, err)
+			}
+			column.Format = layout
+		}
+		schema.Columns[i] = column
+	}
+	return schema, nil
+}
+
+// parseCSVWTitles parses titles, which may be a string or an array of
+// strings.
+func parseCSVWTitles(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var title string
+	if err := json.Unmarshal(raw, &title); err == nil {
+		return []string{title}, nil
+	}
+	var titles []string
+	err := json.Unmarshal(raw, &titles)
+	return titles, err
+}
+
+// --------------------------------------------------------------------------
+// Date formats
+//
+// Table Schema describes dates with strftime directives, and CSVW with
+// Unicode (UTS #35) patterns, rather than Go layouts.  Only the parts
+// of a layout which all three can express are converted.
+
+type formatElement struct {
+	layout   string
+	strftime string
+	uts35    string
+}
+
+// formatElements are in the order they must be matched in a layout,
+// longest first.
+var formatElements = []formatElement{
+	{"January", "%B", "MMMM"},
+	{"Monday", "%A", "EEEE"},
+	{"Z07:00", "", "XXX"},
+	{"-07:00", "", "xxx"},
+	{"Z0700", "", "XX"},
+	{"-0700", "%z", "xx"},
+	{".000000", ".%f", ".SSSSSS"},
+	{".000", "", ".SSS"},
+	{"2006", "%Y", "yyyy"},
+	{"Jan", "%b", "MMM"},
+	{"Mon", "%a", "EEE"},
+	{"MST", "%Z", "zzz"},
+	{"PM", "%p", "a"},
+	{"01", "%m", "MM"},
+	{"02", "%d", "dd"},
+	{"03", "%I", "hh"},
+	{"04", "%M", "mm"},
+	{"05", "%S", "ss"},
+	{"06", "%y", "yy"},
+	{"15", "%H", "HH"},
+}
+
+// convertLayout converts a Go layout, using convert to translate each
+// of its elements and literal to escape the text between them.
+func convertLayout(layout string, convert func(formatElement) string, literal func(string) string) (string, error) {
+	var out, text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			out.WriteString(literal(text.String()))
+			text.Reset()
+		}
+	}
+
+next:
+	for rest := layout; rest != ""; {
+		for _, element := range formatElements {
+			if strings.HasPrefix(rest, element.layout) {
+				converted := convert(element)
+				if converted == "" {
+					return "", fmt.Errorf("cannot convert %q in layout %q", element.layout, layout)
+				}
+				flush()
+				out.WriteString(converted)
+				rest = rest[len(element.layout):]
+				continue next
+			}
+		}
+		if c := rest[0]; c >= '0' && c <= '9' || c == '_' {
+			return "", fmt.Errorf("cannot convert layout %q", layout)
+		}
+		text.WriteByte(rest[0])
+		rest = rest[1:]
+	}
+	flush()
+	return out.String(), nil
+}
+
+// layoutToStrftime converts a Go layout to strftime directives.
+func layoutToStrftime(layout string) (string, error) {
+	return convertLayout(layout, func(e formatElement) string {
+		return e.strftime
+	}, func(s string) string {
+		return strings.Replace(s, "%", "%%", -1)
+	})
+}
+
+// strftimeToLayout converts strftime directives to a Go layout.
+func strftimeToLayout(format string) (string, error) {
+	var out strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			out.WriteByte(format[i])
+			continue
+		}
+		if i+1 == len(format) {
+			return "", fmt.Errorf("cannot convert format %q", format)
+		}
+		i++
+		directive := format[i-1 : i+1]
+		if directive == "%%" {
+			out.WriteByte('%')
+			continue
+		}
+		found := false
+		for _, element := range formatElements {
+			if element.strftime == directive {
+				out.WriteString(element.layout)
+				found = true
+				break
+			}
+			// %f follows a literal dot.
+			if directive == "%f" && element.strftime == ".%f" {
+				out.WriteString(element.layout[1:])
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", fmt.Errorf("cannot convert %q in format %q", directive, format)
+		}
+	}
+	return out.String(), nil
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// layoutToUTS35 converts a Go layout to a UTS #35 pattern.
+func layoutToUTS35(layout string) (string, error) {
+	return convertLayout(layout, func(e formatElement) string {
+		return e.uts35
+	}, func(s string) string {
+		// Letters are pattern characters, so they must be quoted.
+		var out strings.Builder
+		quoted := false
+		for i := 0; i < len(s); i++ {
+			c := s[i]
+			if isASCIILetter(c) != quoted {
+				out.WriteByte('\'')
+				quoted = !quoted
+			}
+			if c == '\'' {
+				out.WriteByte('\'')
+			}
+			out.WriteByte(c)
+		}
+		if quoted {
+			out.WriteByte('\'')
+		}
+		return out.String()
+	})
+}
+
+// uts35ToLayout converts a UTS #35 pattern to a Go layout.
+func uts35ToLayout(pattern string) (string, error) {
+	var out strings.Builder
+	for i := 0; i < len(pattern); {
+		c := pattern[i]
+		switch {
+		case c == '\'':
+			if i+1 < len(pattern) && pattern[i+1] == '\'' {
+				out.WriteByte('\'')
+				i += 2
+				continue
+			}
+			end := strings.IndexByte(pattern[i+1:], '\'')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated quote in pattern %q", pattern)
+			}
+			out.WriteString(pattern[i+1 : i+1+end])
+			i += end + 2
+		case isASCIILetter(c):
+			j := i
+			for j < len(pattern) && pattern[j] == c {
+				j++
+			}
+			run := pattern[i:j]
+			found := false
+			for _, element := range formatElements {
+				// Fractional seconds follow a literal dot.
+				if element.uts35 == run || element.uts35 == "."+run {
+					out.WriteString(strings.TrimPrefix(element.layout, "."))
+					found = true
+					break
+				}
+			}
+			if !found {
+				return "", fmt.Errorf("cannot convert %q in pattern %q", run, pattern)
+			}
+			i = j
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,273 @@
+package commando
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type describedAddress struct {
+	City string `csv:"city,town"`
+}
+
+type describedStruct struct {
+	ID      int64      `csv:"id,required"`
+	Name    string     `csv:"name"`
+	Price   float32    `csv:"price"`
+	Paid    bool       `csv:"paid"`
+	Created time.Time  `csv:"created"`
+	Shipped *time.Time `csv:"shipped,omitempty"`
+	Tags    []string   `csv:"tags"`
+	Level   TestLevel  `csv:"level"`
+	Count   uint8
+	Address *describedAddress `csv:"-"`
+}
+
+// TestLevel is a renamed int with its own conversion.
+type TestLevel int
+
+func (l *TestLevel) UnmarshalCSV(s string) error {
+	*l = TestLevel(len(s))
+	return nil
+}
+
+func TestDescribeSchema(t *testing.T) {
+	t.Parallel()
+
+	schema, err := DescribeSchema(&describedStruct{})
+	require.NoError(t, err)
+	assert.Equal(t, &Schema{Columns: []Column{
+		{Name: "id", Type: TypeInteger, Required: true},
+		{Name: "name", Type: TypeString},
+		{Name: "price", Type: TypeNumber},
+		{Name: "paid", Type: TypeBoolean},
+		{Name: "created", Type: TypeDateTime},
+		{Name: "shipped", Type: TypeDateTime},
+		{Name: "tags", Type: TypeArray},
+		{Name: "level", Type: TypeString},
+		{Name: "Count", Type: TypeInteger},
+		{Name: "city", Aliases: []string{"town"}, Type: TypeString},
+	}}, schema)
+
+	_, err = DescribeSchema(Record{})
+	assert.Error(t, err)
+}
+
+func TestDescribeSchema_Enum(t *testing.T) {
+	t.Parallel()
+
+	schema, err := DescribeSchema(enumStruct{})
+	require.NoError(t, err)
+	assert.Equal(t, []Column{
+		{Name: "name", Type: TypeString},
+		{Name: "status", Type: TypeString, Required: true, Enum: []string{"pending", "active", "closed"}},
+		{Name: "level", Type: TypeString, Enum: []string{"low", "high"}},
+	}, schema.Columns)
+
+	data, err := schema.MarshalTableSchema()
+	require.NoError(t, err)
+	parsed, err := ParseTableSchema(data)
+	require.NoError(t, err)
+	assert.Equal(t, schema, parsed)
+
+	// The schema accepts the labels the Marshaller writes.
+	buf := new(bytes.Buffer)
+	m, err := NewMarshaller(&enumStruct{}, csv.NewWriter(buf))
+	require.NoError(t, err)
+	high := uint(9)
+	require.NoError(t, m.Write(&enumStruct{Name: "a", Status: 1, Level: &high}))
+	require.NoError(t, m.Write(&enumStruct{Name: "b", Status: 2}))
+	require.NoError(t, m.Flush())
+
+	um, err := (&Config{Schema: parsed}).NewUnmarshaller(csv.NewReader(buf))
+	require.NoError(t, err)
+	out, err := um.ReadAll(context.Background(), StopOnError)
+	require.NoError(t, err)
+	records := out.([]Record)
+	require.Len(t, records, 2)
+	assert.Equal(t, "active", records[0].Value("status"))
+	assert.Equal(t, "high", records[0].Value("level"))
+	assert.Equal(t, "closed", records[1].Value("status"))
+	assert.Nil(t, records[1].Value("level"))
+
+	um, err = (&Config{Schema: parsed}).NewUnmarshaller(csv.NewReader(strings.NewReader("name,status\nc,open\n")))
+	require.NoError(t, err)
+	_, err = um.Read()
+	assert.EqualError(t, err, `on line 2, column 3: cannot assign column "status" at index 1: unknown value "open", expected one of ["pending" "active" "closed"]`)
+}
+
+func TestRequiredTag(t *testing.T) {
+	t.Parallel()
+
+	// Without EnforceRequired, required only describes the field.
+	um, err := NewUnmarshaller(describedStruct{}, csv.NewReader(strings.NewReader("name\na\n")))
+	require.NoError(t, err)
+	out, err := um.Read()
+	require.NoError(t, err)
+	assert.Equal(t, describedStruct{Name: "a"}, out)
+
+	c := &Config{Holder: describedStruct{}, EnforceRequired: true}
+	_, err = c.NewUnmarshaller(csv.NewReader(strings.NewReader("name\n")))
+	assert.EqualError(t, err, `missing required column "id"`)
+
+	um, err = c.NewUnmarshaller(csv.NewReader(strings.NewReader("id,name\n1,a\n ,b\n")))
+	require.NoError(t, err)
+	_, err = um.Read()
+	require.NoError(t, err)
+	_, err = um.Read()
+	assert.EqualError(t, err, `on line 3, column 1: cannot assign field "id" at index 0 through index chain [0]: value is required`)
+}
+
+type requiredKeyStruct struct {
+	Required string `csv:"required"`
+	Other    string `csv:"other,required"`
+}
+
+func TestRequiredTag_Key(t *testing.T) {
+	t.Parallel()
+
+	// A column can be called "required", since the first entry of a
+	// tag is always a key.
+	c := &Config{Holder: requiredKeyStruct{}, EnforceRequired: true}
+	um, err := c.NewUnmarshaller(csv.NewReader(strings.NewReader("required,other\nyes,b\n")))
+	require.NoError(t, err)
+	out, err := um.Read()
+	require.NoError(t, err)
+	assert.Equal(t, requiredKeyStruct{Required: "yes", Other: "b"}, out)
+
+	schema, err := DescribeSchema(requiredKeyStruct{})
+	require.NoError(t, err)
+	assert.Equal(t, []Column{
+		{Name: "required", Type: TypeString},
+		{Name: "other", Type: TypeString, Required: true},
+	}, schema.Columns)
+}
+
+func TestTableSchema(t *testing.T) {
+	t.Parallel()
+
+	schema := &Schema{Columns: []Column{
+		{Name: "id", Type: TypeInteger, Required: true},
+		{Name: "name", Aliases: []string{"full name"}},
+		{Name: "date", Type: TypeDate, Format: "02/01/2006"},
+		{Name: "at", Type: TypeDateTime, Format: time.RFC3339},
+	}}
+	data, err := schema.MarshalTableSchema()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"fields": [
+		{"name": "id", "type": "integer", "constraints": {"required": true}},
+		{"name": "name", "type": "string"},
+		{"name": "date", "type": "date", "format": "%d/%m/%Y"},
+		{"name": "at", "type": "datetime"}
+	]}`, string(data))
+
+	parsed, err := ParseTableSchema(data)
+	require.NoError(t, err)
+	assert.Equal(t, &Schema{Columns: []Column{
+		{Name: "id", Type: TypeInteger, Required: true},
+		{Name: "name", Type: TypeString},
+		{Name: "date", Type: TypeDate, Format: "02/01/2006"},
+		{Name: "at", Type: TypeDateTime},
+	}}, parsed)
+
+	_, err = ParseTableSchema([]byte(`{"fields": [{"name": "g", "type": "geopoint"}]}`))
+	assert.EqualError(t, err, `field "g": unsupported type "geopoint"`)
+
+	_, err = ParseTableSchema([]byte(`{"fields": [{"name": "d", "type": "date", "format": "%j"}]}`))
+	assert.EqualError(t, err, `field "d": cannot convert "%j" in format "%j"`)
+}
+
+func TestCSVW(t *testing.T) {
+	t.Parallel()
+
+	schema := &Schema{Columns: []Column{
+		{Name: "id", Type: TypeInteger, Required: true},
+		{Name: "name", Aliases: []string{"full name"}, Default: "anonymous"},
+		{Name: "date", Type: TypeDate, Format: "02/01/2006"},
+		{Name: "at", Type: TypeDateTime, Format: "2006-01-02T15:04:05.000Z07:00"},
+	}}
+	data, err := schema.MarshalCSVW("orders.csv")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"@context": "http://www.w3.org/ns/csvw",
+		"url": "orders.csv",
+		"tableSchema": {"columns": [
+			{"name": "id", "datatype": "integer", "required": true},
+			{"name": "name", "titles": ["name", "full name"], "datatype": "string", "default": "anonymous"},
+			{"name": "date", "datatype": {"base": "date", "format": "dd/MM/yyyy"}},
+			{"name": "at", "datatype": {"base": "dateTime", "format": "yyyy-MM-dd'T'HH:mm:ss.SSSXXX"}}
+		]}
+	}`, string(data))
+
+	parsed, err := ParseCSVW(data)
+	require.NoError(t, err)
+	schema.Columns[1].Type = TypeString
+	assert.Equal(t, schema, parsed)
+
+	parsed, err = ParseCSVW([]byte(`{
+		"@context": ["http://www.w3.org/ns/csvw", {"@language": "en"}],
+		"tableSchema": {"columns": [{"titles": "Amount", "datatype": "decimal"}]}
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, &Schema{Columns: []Column{{Name: "Amount", Type: TypeNumber}}}, parsed)
+
+	_, err = ParseCSVW([]byte(`{"@context": "http://www.w3.org/ns/csvw"}`))
+	assert.EqualError(t, err, "no tableSchema")
+}
+
+func TestParsedSchema_Unmarshal(t *testing.T) {
+	t.Parallel()
+
+	schema, err := ParseTableSchema([]byte(`{"fields": [
+		{"name": "id", "type": "integer", "constraints": {"required": true}},
+		{"name": "date", "type": "date", "format": "%d/%m/%Y"}
+	]}`))
+	require.NoError(t, err)
+
+	um, err := (&Config{Schema: schema}).NewUnmarshaller(csv.NewReader(strings.NewReader("id,date\n1,03/02/2020\n")))
+	require.NoError(t, err)
+	out, err := um.Read()
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2020, 2, 3, 0, 0, 0, 0, time.UTC), out.(Record).Value("date"))
+}
+
+func TestLayoutConversion(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		layout, strftime, uts35 string
+	}{
+		{"2006-01-02", "%Y-%m-%d", "yyyy-MM-dd"},
+		{"Mon, 02 Jan 2006 15:04:05 MST", "%a, %d %b %Y %H:%M:%S %Z", "EEE, dd MMM yyyy HH:mm:ss zzz"},
+		{"03:04 PM", "%I:%M %p", "hh:mm a"},
+		{"2006-01-02 at 15:04:05.000000 -0700", "%Y-%m-%d at %H:%M:%S.%f %z", "yyyy-MM-dd 'at' HH:mm:ss.SSSSSS xx"},
+		{"January 2, 100%", "", ""},
+		{"o'clock 15", "o'clock %H", "'o''''clock' HH"},
+	} {
+		strftime, err := layoutToStrftime(test.layout)
+		if test.strftime == "" {
+			assert.Error(t, err, test.layout)
+		} else if assert.NoError(t, err, test.layout) {
+			assert.Equal(t, test.strftime, strftime)
+			layout, err := strftimeToLayout(strftime)
+			assert.NoError(t, err)
+			assert.Equal(t, test.layout, layout)
+		}
+
+		uts35, err := layoutToUTS35(test.layout)
+		if test.uts35 == "" {
+			assert.Error(t, err, test.layout)
+		} else if assert.NoError(t, err, test.layout) {
+			assert.Equal(t, test.uts35, uts35)
+			layout, err := uts35ToLayout(uts35)
+			assert.NoError(t, err)
+			assert.Equal(t, test.layout, layout)
+		}
+	}
+}
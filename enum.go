@@ -0,0 +1,127 @@
+package commando
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// --------------------------------------------------------------------------
+// Enums
+//
+// The tag option enum=pending:0|active:1 maps labels in the file to
+// values of the field: labels are converted to their values when
+// reading, and values to their labels when writing.  Values are
+// written as commando writes the field's kind, so that 1.5 rather
+// than 1.50 names a float64, and a type with a String method is still
+// named by its number.
+
+// enum maps the labels of a field to its values.
+type enum struct {
+	labels  []string
+	byLabel map[string]string
+	byValue map[string]string
+}
+
+// parseEnum parses the value of an enum tag option.
+func parseEnum(spec string) (*enum, error) {
+	e := &enum{
+		byLabel: make(map[string]string),
+		byValue: make(map[string]string),
+	}
+	for _, entry := range strings.Split(spec, "|") {
+		i := strings.LastIndex(entry, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid enum entry %q, must be label:value", entry)
+		}
+		label, value := entry[:i], entry[i+1:]
+		if _, ok := e.byLabel[label]; ok {
+			return nil, fmt.Errorf("duplicate enum label %q", label)
+		}
+		if _, ok := e.byValue[value]; ok {
+			return nil, fmt.Errorf("duplicate enum value %q", value)
+		}
+		e.labels = append(e.labels, label)
+		e.byLabel[label] = value
+		e.byValue[value] = label
+	}
+	return e, nil
+}
+
+// enumDecoder returns a decodeFunc which decodes the value of the
+// label it's given.  Empty values are decoded as they are.
+func enumDecoder(e *enum, decode decodeFunc) decodeFunc {
+	return func(field reflect.Value, value string) error {
+		label := strings.TrimSpace(value)
+		if label == "" {
+			return decode(field, value)
+		}
+		v, ok := e.byLabel[label]
+		if !ok {
+			return fmt.Errorf("unknown label %q, expected one of %q", label, e.labels)
+		}
+		return decode(field, v)
+	}
+}
+
+// enumEncoder returns an encodeFunc which encodes the label of the
+// field's value.  Empty values, such as nil pointers, are encoded as
+// they are.
+func enumEncoder(e *enum, encode encodeFunc) encodeFunc {
+	return func(field reflect.Value) (string, error) {
+		value, err := encode(field)
+		if err != nil || value == "" {
+			return value, err
+		}
+		label, ok := e.byValue[value]
+		if !ok {
+			return "", fmt.Errorf("value %q has no label", value)
+		}
+		return label, nil
+	}
+}
+
+// compileEnumDecoder returns a decodeFunc for enum fields of type t,
+// which sets them by their kind rather than by any unmarshalling
+// methods, since the values in the tag are written that way.
+func compileEnumDecoder(t reflect.Type, omitEmpty bool) decodeFunc {
+	if t.Kind() != reflect.Ptr {
+		if decode := kindDecoder(t.Kind()); decode != nil {
+			return decode
+		}
+		return compileDecoder(t, omitEmpty)
+	}
+	elemType := t.Elem()
+	decodeElem := kindDecoder(elemType.Kind())
+	if decodeElem == nil {
+		return compileDecoder(t, omitEmpty)
+	}
+	return func(field reflect.Value, value string) error {
+		if omitEmpty && value == "" {
+			return nil
+		}
+		if field.IsNil() {
+			field.Set(reflect.New(elemType))
+		}
+		return decodeElem(field.Elem(), value)
+	}
+}
+
+// compileEnumEncoder returns an encodeFunc for enum fields of type t,
+// which formats them by their kind rather than by any marshalling
+// methods, so that their values can be looked up in the enum.
+func compileEnumEncoder(t reflect.Type) encodeFunc {
+	if t.Kind() == reflect.Ptr {
+		encodeElem := compileEnumEncoder(t.Elem())
+		return func(field reflect.Value) (string, error) {
+			if field.IsNil() {
+				return "", nil
+			}
+			return encodeElem(field.Elem())
+		}
+	}
+	if encode := kindEncoder(t.Kind()); encode != nil {
+		return encode
+	}
+	return compileEncoder(t)
+}
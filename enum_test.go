@@ -0,0 +1,116 @@
+package commando
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type enumStruct struct {
+	Name   string `csv:"name"`
+	Status int    `csv:"status,required,enum=pending:0|active:1|closed:2"`
+	Level  *uint  `csv:"level,omitempty,enum=low:1|high:9"`
+}
+
+func TestEnum_Unmarshal(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{Holder: &enumStruct{}, EnforceRequired: true}
+	um, err := c.NewUnmarshaller(csv.NewReader(strings.NewReader("name,status,level\na,active,high\nb, closed ,\nc,1,\nd,,low\n")))
+	require.NoError(t, err)
+
+	out, err := um.Read()
+	require.NoError(t, err)
+	high := uint(9)
+	assert.Equal(t, &enumStruct{Name: "a", Status: 1, Level: &high}, out)
+
+	out, err = um.Read()
+	require.NoError(t, err)
+	assert.Equal(t, &enumStruct{Name: "b", Status: 2}, out)
+
+	_, err = um.Read()
+	assert.EqualError(t, err, `on line 4, column 3: cannot assign field "status" at index 1 through index chain [1]: unknown label "1", expected one of ["pending" "active" "closed"]`)
+
+	_, err = um.Read()
+	assert.EqualError(t, err, `on line 5, column 3: cannot assign field "status" at index 1 through index chain [1]: value is required`)
+}
+
+func TestEnum_Marshal(t *testing.T) {
+	t.Parallel()
+
+	buf := new(bytes.Buffer)
+	m, err := NewMarshaller(&enumStruct{}, csv.NewWriter(buf))
+	require.NoError(t, err)
+
+	low := uint(1)
+	require.NoError(t, m.Write(&enumStruct{Name: "a", Status: 2, Level: &low}))
+	require.NoError(t, m.Write(&enumStruct{Name: "b"}))
+	assert.EqualError(t, m.Write(&enumStruct{Status: 3}), `value "3" has no label`)
+	require.NoError(t, m.Flush())
+
+	assert.Equal(t, "name,status,level\na,closed,low\nb,pending,\n", buf.String())
+}
+
+// stringerStatus has methods which name its values differently from
+// its enum labels, and which enums don't use.
+type stringerStatus int
+
+func (s stringerStatus) String() string {
+	return [...]string{"Pending", "Active"}[s]
+}
+
+func (s *stringerStatus) UnmarshalText([]byte) error {
+	return errors.New("not used by enums")
+}
+
+type stringerStruct struct {
+	Status stringerStatus  `csv:"status,enum=pending:0|active:1"`
+	Prev   *stringerStatus `csv:"prev,omitempty,enum=pending:0|active:1"`
+}
+
+func TestEnum_Stringer(t *testing.T) {
+	t.Parallel()
+
+	buf := new(bytes.Buffer)
+	m, err := NewMarshaller(&stringerStruct{}, csv.NewWriter(buf))
+	require.NoError(t, err)
+	pending := stringerStatus(0)
+	require.NoError(t, m.Write(&stringerStruct{Status: 1, Prev: &pending}))
+	require.NoError(t, m.Write(&stringerStruct{}))
+	require.NoError(t, m.Flush())
+	assert.Equal(t, "status,prev\nactive,pending\npending,\n", buf.String())
+
+	um, err := NewUnmarshaller(&stringerStruct{}, csv.NewReader(buf))
+	require.NoError(t, err)
+	out, err := um.ReadAll(context.Background(), StopOnError)
+	require.NoError(t, err)
+	assert.Equal(t, []*stringerStruct{{Status: 1, Prev: &pending}, {}}, out)
+}
+
+func TestEnum_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		holder interface{}
+		err    string
+	}{
+		{&struct {
+			A int `csv:"a,enum=x"`
+		}{}, `field A: invalid enum entry "x", must be label:value`},
+		{&struct {
+			A int `csv:"a,enum=x:1|x:2"`
+		}{}, `field A: duplicate enum label "x"`},
+		{&struct {
+			A int `csv:"a,enum=x:1|y:1"`
+		}{}, `field A: duplicate enum value "1"`},
+	} {
+		_, err := NewUnmarshaller(test.holder, csv.NewReader(strings.NewReader("a\n")))
+		assert.EqualError(t, err, test.err)
+	}
+}
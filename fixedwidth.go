@@ -0,0 +1,183 @@
+package commando
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// fixedWidthLayout is the column layout of a fixed-width file, as
+// described by the width, align and pad options of a holder's csv
+// struct tags, e.g.:
+//
+//	Amount int `csv:"amount,width=10,align=right,pad=0"`
+//
+// Columns are laid out in struct field order.  Values are left
+// aligned and padded with spaces unless the tag says otherwise.
+type fixedWidthLayout struct {
+	names   []string
+	columns []fieldInfo
+}
+
+func newFixedWidthLayout(holder interface{}) (*fixedWidthLayout, error) {
+	structInfo, err := getHolderStructInfo(holder)
+	if err != nil {
+		return nil, err
+	}
+	if len(structInfo.Fields) == 0 {
+		return nil, ErrNoStructTags
+	}
+
+	layout := &fixedWidthLayout{columns: structInfo.Fields}
+	for _, field := range structInfo.Fields {
+		if field.width == 0 {
+			return nil, fmt.Errorf("field %q has no width", field.getFirstKey())
+		}
+		layout.names = append(layout.names, field.getFirstKey())
+	}
+	return layout, nil
+}
+
+// split breaks line into one cell per column, removing padding.
+// Missing trailing columns are returned as empty cells.
+func (l *fixedWidthLayout) split(line string) []string {
+	record := make([]string, len(l.columns))
+	for i, column := range l.columns {
+		cell := line
+		n := 0
+		for j := range line {
+			if n == column.width {
+				cell = line[:j]
+				break
+			}
+			n++
+		}
+		line = line[len(cell):]
+
+		if column.align == alignRight {
+			record[i] = strings.TrimLeft(cell, string(column.pad))
+		} else {
+			record[i] = strings.TrimRight(cell, string(column.pad))
+		}
+	}
+	return record
+}
+
+// join pads every cell of record to its column's width and
+// concatenates them.
+func (l *fixedWidthLayout) join(record []string) (string, error) {
+	if len(record) != len(l.columns) {
+		return "", fmt.Errorf("expected %d columns, but got %d", len(l.columns), len(record))
+	}
+
+	var b strings.Builder
+	for i, column := range l.columns {
+		cell := record[i]
+		n := utf8.RuneCountInString(cell)
+		if n > column.width {
+			return "", fmt.Errorf("value %q of %q is wider than %d", cell, l.names[i], column.width)
+		}
+		padding := strings.Repeat(string(column.pad), column.width-n)
+		if column.align == alignRight {
+			b.WriteString(padding)
+			b.WriteString(cell)
+		} else {
+			b.WriteString(cell)
+			b.WriteString(padding)
+		}
+	}
+	return b.String(), nil
+}
+
+// FixedWidthReader is a Reader for fixed-width files, laid out
+// according to the csv struct tags of a holder.
+//
+// Fixed-width files have no header row; an Unmarshaller created with
+// a FixedWidthReader maps columns to the holder's fields by position.
+type FixedWidthReader struct {
+	layout *fixedWidthLayout
+	reader *bufio.Reader
+}
+
+// NewFixedWidthReader returns a FixedWidthReader which reads from r
+// using the layout described by holder's struct tags.
+func NewFixedWidthReader(holder interface{}, r io.Reader) (*FixedWidthReader, error) {
+	layout, err := newFixedWidthLayout(holder)
+	if err != nil {
+		return nil, err
+	}
+	return &FixedWidthReader{layout: layout, reader: bufio.NewReader(r)}, nil
+}
+
+func (r *FixedWidthReader) headers() []string {
+	return r.layout.names
+}
+
+// Read reads one line and splits it into cells.  Blank lines are
+// skipped.
+func (r *FixedWidthReader) Read() ([]string, error) {
+	for {
+		line, err := r.reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return nil, err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			continue
+		}
+		return r.layout.split(line), nil
+	}
+}
+
+// FixedWidthWriter is a Writer for fixed-width files, laid out
+// according to the csv struct tags of a holder.
+//
+// Fixed-width files have no header row, so a Marshaller created with
+// a FixedWidthWriter doesn't write one.
+type FixedWidthWriter struct {
+	layout *fixedWidthLayout
+	writer *bufio.Writer
+}
+
+// NewFixedWidthWriter returns a FixedWidthWriter which writes to w
+// using the layout described by holder's struct tags.
+func NewFixedWidthWriter(holder interface{}, w io.Writer) (*FixedWidthWriter, error) {
+	layout, err := newFixedWidthLayout(holder)
+	if err != nil {
+		return nil, err
+	}
+	return &FixedWidthWriter{layout: layout, writer: bufio.NewWriter(w)}, nil
+}
+
+func (w *FixedWidthWriter) headers() []string {
+	return w.layout.names
+}
+
+// Write writes record as a single padded line.  It returns an error
+// if a value doesn't fit in its column.
+func (w *FixedWidthWriter) Write(record []string) error {
+	line, err := w.layout.join(record)
+	if err != nil {
+		return err
+	}
+	if _, err := w.writer.WriteString(line); err != nil {
+		return err
+	}
+	return w.writer.WriteByte('\n')
+}
+
+// Flush writes any buffered data to the underlying io.Writer.  To
+// check if an error occurred during the Flush, call Error.
+func (w *FixedWidthWriter) Flush() {
+	w.writer.Flush()
+}
+
+// Error reports any error that has occurred during a previous Write
+// or Flush.
+func (w *FixedWidthWriter) Error() error {
+	_, err := w.writer.Write(nil)
+	return err
+}
@@ -0,0 +1,104 @@
+package commando
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fixedWidthSample struct {
+	Code   string  `csv:"code,width=3"`
+	Name   string  `csv:"name,width=8"`
+	Amount int     `csv:"amount,width=6,align=right,pad=0"`
+	Rate   float64 `csv:"rate,width=5,align=right"`
+}
+
+const fixedWidthContents = `D01Alice   00012300.25
+D02Bob     000045  1.5
+
+D03Çedille 000000    0
+`
+
+func TestFixedWidthReader(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewFixedWidthReader(fixedWidthSample{}, strings.NewReader(fixedWidthContents))
+	require.NoError(t, err)
+
+	um, err := NewUnmarshaller(fixedWidthSample{}, r)
+	require.NoError(t, err)
+
+	out, err := um.ReadAll(context.Background(), StopOnError)
+	require.NoError(t, err)
+	assert.Equal(t, []fixedWidthSample{
+		{"D01", "Alice", 123, 0.25},
+		{"D02", "Bob", 45, 1.5},
+		{"D03", "Çedille", 0, 0},
+	}, out)
+}
+
+func TestFixedWidthReader_ErrorLineNumbers(t *testing.T) {
+	t.Parallel()
+
+	contents := "D01Alice   000123 0.25\nD02Bob     00004X  1.5\n"
+	r, err := NewFixedWidthReader(fixedWidthSample{}, strings.NewReader(contents))
+	require.NoError(t, err)
+
+	um, err := NewUnmarshaller(fixedWidthSample{}, r)
+	require.NoError(t, err)
+
+	_, err = um.Read()
+	require.NoError(t, err)
+
+	_, err = um.Read()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2", "Expected the error to mention line number")
+}
+
+func TestFixedWidthWriter(t *testing.T) {
+	t.Parallel()
+
+	out := new(bytes.Buffer)
+	w, err := NewFixedWidthWriter(&fixedWidthSample{}, out)
+	require.NoError(t, err)
+
+	m, err := NewMarshaller(&fixedWidthSample{}, w)
+	require.NoError(t, err)
+
+	require.NoError(t, m.WriteAll([]*fixedWidthSample{
+		{"D01", "Alice", 123, 0.25},
+		{"D02", "Bob", 45, 1.5},
+	}))
+	require.NoError(t, m.Flush())
+	assert.Equal(t, "D01Alice   000123 0.25\nD02Bob     000045  1.5\n", out.String())
+
+	err = m.Write(&fixedWidthSample{Name: "Bartholomew"})
+	require.Error(t, err, "Expected an error for a value wider than its column")
+}
+
+func TestFixedWidthLayout_Errors(t *testing.T) {
+	t.Parallel()
+
+	type noWidth struct {
+		Code string `csv:"code,width=3"`
+		Name string `csv:"name"`
+	}
+	_, err := NewFixedWidthReader(noWidth{}, strings.NewReader(""))
+	assert.Error(t, err, "Expected an error for a field with no width")
+
+	type badAlign struct {
+		Code string `csv:"code,width=3,align=middle"`
+	}
+	_, err = NewFixedWidthWriter(badAlign{}, new(bytes.Buffer))
+	assert.Error(t, err, "Expected an error for an invalid alignment")
+
+	type badPad struct {
+		Code string `csv:"code,width=3,pad=ab"`
+	}
+	_, err = NewFixedWidthWriter(badPad{}, new(bytes.Buffer))
+	assert.Error(t, err, "Expected an error for an invalid pad")
+}
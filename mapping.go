@@ -0,0 +1,172 @@
+package commando
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Warning is a problem with a file which doesn't stop it being read.
+type Warning struct {
+	// Line is the line the warning is about, or 0 if it's about the
+	// headers.
+	Line int
+
+	// Header is the header of the column the warning is about, if
+	// any.
+	Header string
+
+	// Message describes the problem.
+	Message string
+}
+
+func (w Warning) Error() string {
+	if w.Line > 0 {
+		return fmt.Sprintf("on line %d: %s", w.Line, w.Message)
+	}
+	return w.Message
+}
+
+// warn passes w to the WarningHandler, if there is one.
+func (vc *validConfig) warn(w Warning) {
+	if vc.WarningHandler != nil {
+		vc.WarningHandler(w)
+	}
+}
+
+// warnHeaders warns about each column which isn't unmarshalled into
+// anything, and each field which no column is unmarshalled into.
+func (vc *validConfig) warnHeaders() {
+	if vc.WarningHandler == nil {
+		return
+	}
+	for _, header := range vc.unmappedHeaders() {
+		vc.warn(Warning{Header: header, Message: fmt.Sprintf("column %q is ignored", header)})
+	}
+	for _, key := range vc.unmatchedFields() {
+		vc.warn(Warning{Header: key, Message: fmt.Sprintf("no column for field %q", key)})
+	}
+}
+
+// Headers returns the headers of the file being read.
+func (um *Unmarshaller) Headers() []string {
+	return append([]string(nil), um.config.fileHeaders()...)
+}
+
+// Mapping returns what each header is unmarshalled into: the path of
+// a struct field, such as "Customer.City", the name of a Schema
+// column, or, for Record and map holders without a Schema, the
+// header itself.  Headers which aren't unmarshalled into anything
+// are left out.  If a header is repeated, its first column is
+// mapped.
+func (um *Unmarshaller) Mapping() map[string]string {
+	headers, targets := um.config.fileHeaders(), um.config.targets()
+	mapping := make(map[string]string, len(targets))
+	for j, target := range targets {
+		header := headers[j]
+		if _, ok := mapping[header]; !ok && target != "" {
+			mapping[header] = target
+		}
+	}
+	return mapping
+}
+
+// UnmappedHeaders returns the headers, in file order, which aren't
+// unmarshalled into anything.
+func (um *Unmarshaller) UnmappedHeaders() []string {
+	return um.config.unmappedHeaders()
+}
+
+// UnmatchedFields returns the first key of each struct field, or the
+// name of each Schema column, which no header is unmarshalled into.
+func (um *Unmarshaller) UnmatchedFields() []string {
+	return um.config.unmatchedFields()
+}
+
+// fileHeaders returns the headers of the file being read.  With a
+// Schema, vc's headers are its columns instead.
+func (vc *validConfig) fileHeaders() []string {
+	if vc.schema != nil {
+		return vc.schema.fileHeaders
+	}
+	return vc.headers
+}
+
+// targets returns what each of vc's headers is unmarshalled into, as
+// described by Mapping, or "".
+func (vc *validConfig) targets() []string {
+	targets := make([]string, len(vc.fileHeaders()))
+	switch {
+	case vc.variants != nil:
+	case vc.schema != nil:
+		for i, j := range vc.schema.positions {
+			if j >= 0 {
+				targets[j] = vc.schema.names[i]
+			}
+		}
+	case vc.dynamic:
+		copy(targets, vc.headers)
+	default:
+		for j, fieldInfo := range vc.fieldInfoMap {
+			if fieldInfo != nil {
+				targets[j] = fieldPath(vc.outType, fieldInfo.IndexChain)
+			}
+		}
+	}
+	return targets
+}
+
+func (vc *validConfig) unmappedHeaders() []string {
+	var unmapped []string
+	headers := vc.fileHeaders()
+	for j, target := range vc.targets() {
+		if target == "" {
+			unmapped = append(unmapped, headers[j])
+		}
+	}
+	return unmapped
+}
+
+func (vc *validConfig) unmatchedFields() []string {
+	var unmatched []string
+	switch {
+	case vc.variants != nil:
+	case vc.schema != nil:
+		for i, j := range vc.schema.positions {
+			if j < 0 {
+				unmatched = append(unmatched, vc.schema.names[i])
+			}
+		}
+	case vc.dynamic:
+	default:
+		// fieldInfoMap holds copies of the fields, so they're told
+		// apart by their index chains.
+		matched := make(map[string]bool, len(vc.fieldInfoMap))
+		for _, fieldInfo := range vc.fieldInfoMap {
+			if fieldInfo != nil {
+				matched[fmt.Sprint(fieldInfo.IndexChain)] = true
+			}
+		}
+		for _, field := range vc.structInfo.Fields {
+			if !matched[fmt.Sprint(field.IndexChain)] {
+				unmatched = append(unmatched, field.getFirstKey())
+			}
+		}
+	}
+	return unmatched
+}
+
+// fieldPath returns the names of the fields along index, from the
+// struct t, joined by dots.
+func fieldPath(t reflect.Type, index []int) string {
+	names := make([]string, len(index))
+	for i, x := range index {
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		field := t.Field(x)
+		names[i] = field.Name
+		t = field.Type
+	}
+	return strings.Join(names, ".")
+}
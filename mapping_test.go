@@ -0,0 +1,73 @@
+package commando
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mappedAddress struct {
+	City string `csv:"city,town"`
+	Zip  string `csv:"zip"`
+}
+
+type mappedStruct struct {
+	ID      int            `csv:"id"`
+	Name    string         `csv:"name"`
+	Address *mappedAddress `csv:"-"`
+}
+
+func TestUnmarshaller_Mapping(t *testing.T) {
+	t.Parallel()
+
+	var warnings []Warning
+	c := &Config{Holder: mappedStruct{}, WarningHandler: func(w Warning) {
+		warnings = append(warnings, w)
+	}}
+	um, err := c.NewUnmarshaller(csv.NewReader(strings.NewReader(" id,town,notes,id\n")))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{" id", "town", "notes", "id"}, um.Headers())
+	assert.Equal(t, map[string]string{
+		" id":  "ID",
+		"town": "Address.City",
+		"id":   "ID",
+	}, um.Mapping())
+	assert.Equal(t, []string{"notes"}, um.UnmappedHeaders())
+	assert.Equal(t, []string{"name", "zip"}, um.UnmatchedFields())
+	assert.Equal(t, []Warning{
+		{Header: "notes", Message: `column "notes" is ignored`},
+		{Header: "name", Message: `no column for field "name"`},
+		{Header: "zip", Message: `no column for field "zip"`},
+	}, warnings)
+}
+
+func TestUnmarshaller_MappingDynamic(t *testing.T) {
+	t.Parallel()
+
+	um, err := NewUnmarshaller(Record{}, csv.NewReader(strings.NewReader("a,b\n")))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "a", "b": "b"}, um.Mapping())
+	assert.Empty(t, um.UnmappedHeaders())
+	assert.Empty(t, um.UnmatchedFields())
+
+	c := &Config{Schema: &Schema{Columns: []Column{
+		{Name: "id", Aliases: []string{"ID"}},
+		{Name: "name"},
+	}}}
+	um, err = c.NewUnmarshaller(csv.NewReader(strings.NewReader("ID,other\n")))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"ID": "id"}, um.Mapping())
+	assert.Equal(t, []string{"other"}, um.UnmappedHeaders())
+	assert.Equal(t, []string{"name"}, um.UnmatchedFields())
+}
+
+func TestWarning_Error(t *testing.T) {
+	t.Parallel()
+
+	assert.EqualError(t, Warning{Message: "m"}, "m")
+	assert.EqualError(t, Warning{Line: 3, Message: "m"}, "on line 3: m")
+}
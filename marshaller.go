@@ -1,30 +1,39 @@
 package commando
 
 import (
-	"encoding/csv"
 	"fmt"
 	"reflect"
+	"time"
 )
 
 // Marshaller is a CSV to struct marshaller.
 type Marshaller struct {
 	config *validConfig
-	writer *csv.Writer
+	writer Writer
 }
 
 // NewMarshaller is a convenience function which allocates and
 // returns a new Marshaller.
-func NewMarshaller(holder interface{}, writer *csv.Writer) (*Marshaller, error) {
+func NewMarshaller(holder interface{}, writer Writer) (*Marshaller, error) {
 	return (&Config{Holder: holder}).NewMarshaller(writer)
 }
 
-// NewMarshaller creates a marshaller from a csv.Writer.  The CSV
-// header will be immediately written to writer.
-func (c *Config) NewMarshaller(writer *csv.Writer) (*Marshaller, error) {
-	vc, err := c.validate(nil)
+// NewMarshaller creates a marshaller from a Writer.  The CSV header
+// will be immediately written to writer, unless the writer's format
+// has no header row.
+func (c *Config) NewMarshaller(writer Writer) (*Marshaller, error) {
+	// Structs take their headers from their tags.
+	var headers []string
+	if c.Schema == nil && isDynamic(reflect.TypeOf(c.Holder)) {
+		headers = c.Headers
+	}
+	vc, err := c.validate(headers)
 	if err != nil {
 		return nil, err
 	}
+	if vc.dynamic && len(vc.headers) == 0 {
+		return nil, fmt.Errorf("Headers must be set to marshal %s", vc.outType)
+	}
 
 	m := &Marshaller{
 		writer: writer,
@@ -38,20 +47,83 @@ func (c *Config) NewMarshaller(writer *csv.Writer) (*Marshaller, error) {
 }
 
 func (m *Marshaller) writeHeaders() error {
-	return m.writer.Write(m.config.structInfo.headers())
+	if _, ok := m.writer.(headerless); ok || m.config.variants != nil {
+		return nil
+	}
+	var headers []string
+	if m.config.dynamic {
+		headers = m.config.aliasHeaders(m.config.headers)
+	} else {
+		headers = m.config.aliasHeaders(m.config.structInfo.headers())
+	}
+	if err := m.writer.Write(headers); err != nil {
+		return err
+	}
+	if m.config.Observer != nil {
+		m.config.Observer.OnHeader(headers)
+	}
+	return nil
 }
 
+// Write writes record as a CSV row.  If the Holder is a Record or
+// map, record may be a Record, *Record or map[string]string.  With
+// Variants, record may be the Holder of any variant.
+//
+// If the record implements BeforeMarshaller, BeforeMarshalCSV is
+// called first.
 func (m *Marshaller) Write(record interface{}) error {
+	if m.config.Observer == nil {
+		return m.write(record)
+	}
+	start := time.Now()
+	err := m.write(record)
+	m.config.Observer.OnWrite(time.Since(start), err)
+	return err
+}
+
+// write is Write, without the Observer.
+func (m *Marshaller) write(record interface{}) error {
+	if m.config.variants != nil {
+		row, err := m.config.encodeVariant(record)
+		if err != nil {
+			return err
+		}
+		return m.writer.Write(row)
+	}
+	if m.config.dynamic {
+		row, err := m.config.encodeDynamic(record)
+		if err != nil {
+			return err
+		}
+		return m.writer.Write(row)
+	}
+
 	if reflect.TypeOf(record) != m.config.outType {
 		return fmt.Errorf("Expected %q, but got %q", m.config.outType, reflect.TypeOf(record))
 	}
 
-	inValue, inType := getConcreteReflectValueAndType(record) // Get the concrete type
-	inInnerWasPointer := inType.Kind() == reflect.Ptr
+	record, err := m.config.beforeMarshal(record)
+	if err != nil {
+		return err
+	}
+
+	if rm, ok := record.(RecordMarshaller); ok {
+		row, err := rm.MarshalCSVRecord()
+		if err != nil {
+			return err
+		}
+		return m.writer.Write(row)
+	}
+
+	inValue, _ := getConcreteReflectValueAndType(record) // Get the concrete type
 
 	csvHeadersLabels := make([]string, len(m.config.structInfo.Fields))
 	for i, fieldInfo := range m.config.structInfo.Fields {
-		inInnerFieldValue, err := getInnerField(inValue, inInnerWasPointer, fieldInfo.IndexChain) // Get the correct field header <-> position
+		field, ok := fieldForRead(inValue, fieldInfo.IndexChain) // Get the correct field header <-> position
+		if !ok {
+			continue
+		}
+		inInnerFieldValue, err := fieldInfo.encode(field)
 		if err != nil {
 			return err
 		}
@@ -89,5 +161,28 @@ func (m *Marshaller) WriteAll(values interface{}) error {
 
 func (m *Marshaller) Flush() error {
 	m.writer.Flush()
-	return m.writer.Error()
+	err := m.writer.Error()
+	if m.config.Observer != nil {
+		m.config.Observer.OnFlush(err)
+	}
+	return err
+}
+
+// beforeMarshal calls the BeforeMarshalCSV method of record, if it
+// has one, and returns the record to marshal.  If the method needs a
+// pointer but record was passed by value, it's called on a copy, which
+// is returned instead.
+func (vc *validConfig) beforeMarshal(record interface{}) (interface{}, error) {
+	if !vc.beforeMarshaller {
+		return record, nil
+	}
+	if bm, ok := record.(BeforeMarshaller); ok {
+		return record, bm.BeforeMarshalCSV()
+	}
+	copied := reflect.New(reflect.TypeOf(record))
+	copied.Elem().Set(reflect.ValueOf(record))
+	if err := copied.Interface().(BeforeMarshaller).BeforeMarshalCSV(); err != nil {
+		return nil, err
+	}
+	return copied.Elem().Interface(), nil
 }
@@ -3,7 +3,11 @@ package commando
 import (
 	"bytes"
 	"encoding/csv"
+	"errors"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMarshaller(t *testing.T) {
@@ -127,3 +131,39 @@ A,B
 		t.Fatalf("Got unexpected CSV output:\n%q\n", csv)
 	}
 }
+
+type stampedRecord struct {
+	Name  string `csv:"name"`
+	Stamp string `csv:"stamp"`
+}
+
+func (r *stampedRecord) BeforeMarshalCSV() error {
+	if r.Name == "" {
+		return errors.New("no name")
+	}
+	r.Stamp = "stamped"
+	return nil
+}
+
+func TestMarshaller_BeforeMarshalCSV(t *testing.T) {
+	t.Parallel()
+
+	out := new(bytes.Buffer)
+	m, err := NewMarshaller(stampedRecord{}, csv.NewWriter(out))
+	require.NoError(t, err)
+
+	r := stampedRecord{Name: "a"}
+	require.NoError(t, m.Write(r))
+	assert.Equal(t, "", r.Stamp, "record passed by value was modified")
+	assert.EqualError(t, m.Write(stampedRecord{}), "no name")
+	require.NoError(t, m.Flush())
+	assert.Equal(t, "name,stamp\na,stamped\n", out.String())
+
+	out.Reset()
+	m, err = NewMarshaller(&period{}, csv.NewWriter(out))
+	require.NoError(t, err)
+	assert.EqualError(t, m.Write(&period{Start: 1}), "period has no end")
+	require.NoError(t, m.Write(&period{Start: 1, End: 2}))
+	require.NoError(t, m.Flush())
+	assert.Equal(t, "start,end\n1,2\n", out.String())
+}
@@ -0,0 +1,92 @@
+package commando
+
+import (
+	"context"
+	"strings"
+)
+
+// RowMeta describes a row which was read, and where it came from.
+type RowMeta struct {
+	// Row is the row as the Reader returned it.  If the Reader
+	// reuses its records, like a csv.Reader with ReuseRecord set, it
+	// is only valid until the next row is read.
+	Row []string
+
+	// Index is the index of the row among the file's records,
+	// counting from 0 and not counting the header.
+	Index int
+
+	// StartLine and EndLine are the lines the row starts and ends
+	// on, which differ if it has quoted values with newlines.  If the
+	// Reader doesn't report positions, they're the number of records
+	// read so far, including the header.
+	StartLine int
+	EndLine   int
+
+	// Offset and EndOffset are the byte offsets of the start and end
+	// of the row, or -1 if the Reader doesn't report them.
+	Offset    int64
+	EndOffset int64
+}
+
+// readRow reads the next row from the Reader, keeping track of where
+// it came from.
+func (um *Unmarshaller) readRow() ([]string, error) {
+	um.delivered = nil
+	pr, positioned := um.reader.(PositionedReader)
+	offset := int64(-1)
+	if positioned {
+		offset = pr.InputOffset()
+	}
+
+	row, err := um.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	um.line++
+	um.last = RowMeta{
+		Row:       row,
+		Index:     um.index,
+		StartLine: um.line,
+		EndLine:   um.line,
+		Offset:    offset,
+		EndOffset: -1,
+	}
+	um.index++
+
+	if positioned && len(row) > 0 {
+		um.last.StartLine, _ = pr.FieldPos(0)
+		um.last.EndLine = endLine(pr, row)
+		um.last.EndOffset = pr.InputOffset()
+	}
+	um.endLine = um.last.EndLine
+	return row, nil
+}
+
+// endLine returns the line row, the record pr most recently read,
+// ends on.
+func endLine(pr PositionedReader, row []string) int {
+	line, _ := pr.FieldPos(len(row) - 1)
+	return line + strings.Count(row[len(row)-1], "\n")
+}
+
+// ReadWithMeta is Read, also returning the row the record was read
+// from and where it came from.  The RowMeta is returned even if the
+// row couldn't be unmarshalled.
+func (um *Unmarshaller) ReadWithMeta() (interface{}, RowMeta, error) {
+	index := um.index
+	rec, err := um.read(context.Background())
+	if um.index == index {
+		// The Reader failed, so no row was read.
+		return nil, RowMeta{}, err
+	}
+	return rec, um.last, err
+}
+
+// LastRow returns the row most recently read, and where it came from.
+// It mustn't be called while ReadAllCallback is reading with several
+// Workers.  Once ReadAllCallback returns, it's the last row handed to
+// its callbacks, even if more rows were read ahead.
+func (um *Unmarshaller) LastRow() RowMeta {
+	return um.last
+}
@@ -0,0 +1,53 @@
+package commando
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type metaStruct struct {
+	Name string `csv:"name"`
+	Age  int    `csv:"age"`
+}
+
+func TestUnmarshaller_ReadWithMeta(t *testing.T) {
+	t.Parallel()
+
+	data := "name,age\nann,1\n\"bo\nb\",2\ncy,x\n"
+	um, err := NewUnmarshaller(metaStruct{}, csv.NewReader(strings.NewReader(data)))
+	require.NoError(t, err)
+
+	rec, meta, err := um.ReadWithMeta()
+	require.NoError(t, err)
+	assert.Equal(t, metaStruct{Name: "ann", Age: 1}, rec)
+	assert.Equal(t, RowMeta{Row: []string{"ann", "1"}, Index: 0, StartLine: 2, EndLine: 2, Offset: 9, EndOffset: 15}, meta)
+
+	rec, meta, err = um.ReadWithMeta()
+	require.NoError(t, err)
+	assert.Equal(t, metaStruct{Name: "bo\nb", Age: 2}, rec)
+	assert.Equal(t, RowMeta{Row: []string{"bo\nb", "2"}, Index: 1, StartLine: 3, EndLine: 4, Offset: 15, EndOffset: 24}, meta)
+
+	_, meta, err = um.ReadWithMeta()
+	assert.Error(t, err)
+	assert.Equal(t, RowMeta{Row: []string{"cy", "x"}, Index: 2, StartLine: 5, EndLine: 5, Offset: 24, EndOffset: 29}, meta)
+	assert.Equal(t, meta, um.LastRow())
+	assert.Equal(t, "cy,x\n", data[meta.Offset:meta.EndOffset])
+
+	_, meta, err = um.ReadWithMeta()
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, RowMeta{}, meta)
+}
+
+func TestUnmarshaller_LastRowUnpositioned(t *testing.T) {
+	t.Parallel()
+
+	um, err := NewUnmarshaller(raggedStruct{}, raggedRows())
+	require.NoError(t, err)
+	require.NoError(t, um.ReadInto(&raggedStruct{}))
+	assert.Equal(t, RowMeta{Row: []string{"a", "1"}, Index: 0, StartLine: 2, EndLine: 2, Offset: -1, EndOffset: -1}, um.LastRow())
+}
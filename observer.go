@@ -0,0 +1,55 @@
+package commando
+
+import "time"
+
+// Observer is told what Unmarshallers and Marshallers do, so that
+// they can be measured and traced.  With several Workers, OnRow and
+// OnFieldError may be called from several goroutines at once.
+//
+// Embed NopObserver to implement only some of the methods.
+type Observer interface {
+	// OnHeader is called with the headers of a file, once they've
+	// been read or written.
+	OnHeader(headers []string)
+
+	// OnRow is called for each row read, with the line it starts
+	// on, how long it took to unmarshal and the error, if it
+	// couldn't be.
+	OnRow(line int, d time.Duration, err error)
+
+	// OnFieldError is called for each row which couldn't be
+	// unmarshalled because of a value, with the line and header of
+	// the value.
+	OnFieldError(line int, header string, err error)
+
+	// OnWrite is called for each record written, with how long it
+	// took and the error, if any.
+	OnWrite(d time.Duration, err error)
+
+	// OnFlush is called each time a Marshaller is flushed, with the
+	// error, if any.
+	OnFlush(err error)
+}
+
+// NopObserver is an Observer which does nothing.
+type NopObserver struct{}
+
+func (NopObserver) OnHeader([]string)               {}
+func (NopObserver) OnRow(int, time.Duration, error) {}
+func (NopObserver) OnFieldError(int, string, error) {}
+func (NopObserver) OnWrite(time.Duration, error)    {}
+func (NopObserver) OnFlush(error)                   {}
+
+// observeRow tells the Observer, which must be set, about row, which
+// started being unmarshalled at start and was read from line.  err is
+// the error, with its position.
+func (um *Unmarshaller) observeRow(start time.Time, line int, row []string, err error) {
+	o := um.config.Observer
+	o.OnRow(line, time.Since(start), err)
+	if header, _, ok := um.config.errorHeader(row, err); ok {
+		if pe, ok := err.(*PositionError); ok {
+			line = pe.Line
+		}
+		o.OnFieldError(line, header, err)
+	}
+}
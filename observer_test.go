@@ -0,0 +1,95 @@
+package commando
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingObserver records what it's told, except for durations.
+type recordingObserver struct {
+	NopObserver
+	mu     sync.Mutex
+	events []string
+}
+
+func (o *recordingObserver) record(format string, args ...interface{}) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, fmt.Sprintf(format, args...))
+}
+
+func (o *recordingObserver) OnHeader(headers []string) {
+	o.record("header %q", headers)
+}
+
+func (o *recordingObserver) OnRow(line int, d time.Duration, err error) {
+	o.record("row %d %v", line, err != nil)
+}
+
+func (o *recordingObserver) OnFieldError(line int, header string, err error) {
+	o.record("field error %d %s", line, header)
+}
+
+func (o *recordingObserver) OnWrite(d time.Duration, err error) {
+	o.record("write %v", err)
+}
+
+func (o *recordingObserver) OnFlush(err error) {
+	o.record("flush %v", err)
+}
+
+func TestObserver_Unmarshal(t *testing.T) {
+	t.Parallel()
+
+	for _, workers := range []int{0, 2} {
+		o := &recordingObserver{}
+		c := &Config{Holder: metaStruct{}, Observer: o, Workers: workers}
+		um, err := c.NewUnmarshaller(csv.NewReader(strings.NewReader("name,age\n\"a\nb\",1\nc,x\n")))
+		require.NoError(t, err)
+		_, err = um.ReadAll(context.Background(), func(context.Context, error) error {
+			return nil
+		})
+		require.NoError(t, err)
+
+		assert.ElementsMatch(t, []string{
+			`header ["name" "age"]`,
+			"row 2 false",
+			"row 4 true",
+			"field error 4 age",
+		}, o.events)
+	}
+
+	o := &recordingObserver{}
+	um, err := (&Config{Holder: metaStruct{}, Observer: o}).NewUnmarshaller(csv.NewReader(strings.NewReader("name,age\nc,x\n")))
+	require.NoError(t, err)
+	assert.Error(t, um.ReadInto(&metaStruct{}))
+	assert.Equal(t, []string{`header ["name" "age"]`, "row 2 true", "field error 2 age"}, o.events)
+}
+
+func TestObserver_Marshal(t *testing.T) {
+	t.Parallel()
+
+	o := &recordingObserver{}
+	m, err := (&Config{Holder: metaStruct{}, Observer: o}).NewMarshaller(csv.NewWriter(new(bytes.Buffer)))
+	require.NoError(t, err)
+	require.NoError(t, m.WriteAll([]metaStruct{{Name: "a"}, {Name: "b"}}))
+	assert.Error(t, m.Write(1))
+	require.NoError(t, m.Flush())
+
+	assert.Equal(t, []string{
+		`header ["name" "age"]`,
+		"write <nil>",
+		"write <nil>",
+		`write Expected "commando.metaStruct", but got "int"`,
+		"flush <nil>",
+	}, o.events)
+}
@@ -0,0 +1,179 @@
+package commando
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// parallelRow is a row travelling from the reading goroutine,
+// through a worker, to ReadAllCallback.
+type parallelRow struct {
+	// seq is the position of the row in the input, used to restore
+	// the input order.
+	seq    int
+	meta   RowMeta
+	fields []fieldPosition
+	rec    interface{}
+	err    error
+
+	// next is where the Reader had got to once the row was read.
+	next Checkpoint
+
+	// readErr indicates whether err came from the Reader.
+	readErr bool
+}
+
+// readAllParallel implements ReadAllCallback with one goroutine
+// reading rows and Config.Workers goroutines decoding them.
+func (um *Unmarshaller) readAllParallel(ctx context.Context,
+	onSuccess func(context.Context, interface{}) error,
+	onError func(context.Context, error) error,
+) error {
+	workCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	// last is the last row delivered, which LastRow returns once the
+	// reader has stopped, and delivered is where the rows and errors
+	// delivered so far end, for Checkpoint.
+	var last *RowMeta
+	delivered := um.position()
+	defer func() {
+		// Stop the reader and workers, and wait for them so um
+		// isn't used after ReadAllCallback returns.
+		cancel()
+		wg.Wait()
+		if last != nil {
+			um.last = *last
+		}
+		um.delivered = &delivered
+	}()
+
+	workers := um.config.Workers
+	rows := make(chan *parallelRow, workers)
+	results := make(chan *parallelRow, workers)
+	// outstanding limits the rows read but not yet delivered, so that
+	// a slow row doesn't leave the rest of the file waiting in
+	// pending.  A slot is taken before each row is read, and given
+	// back once it's delivered.
+	outstanding := make(chan struct{}, 2*workers)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(rows)
+		for seq := 0; workCtx.Err() == nil; seq++ {
+			select {
+			case outstanding <- struct{}{}:
+			case <-workCtx.Done():
+				return
+			}
+			row, err := um.readRow()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			pr := &parallelRow{seq: seq, next: um.position(), err: err, readErr: err != nil}
+			if err == nil {
+				pr.meta = um.last
+				// The Reader will have moved on by the time the row
+				// is decoded, so ask it where the fields are now.
+				pr.fields = um.fieldPositions(row)
+				// Copy the row, in case the Reader reuses it.
+				pr.meta.Row = append([]string(nil), row...)
+			}
+
+			select {
+			case rows <- pr:
+			case <-workCtx.Done():
+				return
+			}
+		}
+	}()
+
+	var workersWG sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		workersWG.Add(1)
+		go func() {
+			defer workersWG.Done()
+			for pr := range rows {
+				if pr.err == nil {
+					pr.rec, pr.err = um.decodeRow(workCtx, pr.meta.Row, pr.meta.Index, pr.meta.StartLine, pr.fields)
+				}
+
+				select {
+				case results <- pr:
+				case <-workCtx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		workersWG.Wait()
+		close(results)
+	}()
+
+	// finished holds where the rows delivered out of order end, until
+	// the rows before them have been delivered too, so that a
+	// Checkpoint never skips a row which wasn't delivered.
+	finished := map[int]Checkpoint{}
+	nextFinished := 0
+
+	// Rows are counted as they're delivered, so that Stats leave out
+	// rows read ahead of an early stop.
+	deliver := func(pr *parallelRow) error {
+		<-outstanding
+		finished[pr.seq] = pr.next
+		for {
+			next, ok := finished[nextFinished]
+			if !ok {
+				break
+			}
+			delete(finished, nextFinished)
+			nextFinished++
+			delivered = next
+		}
+		if pr.readErr {
+			um.stats.addReadError(pr.err)
+		} else {
+			um.stats.addRow(um.config, pr.meta.Row, pr.err)
+			last = &pr.meta
+		}
+		if pr.err != nil {
+			return onError(ctx, pr.err)
+		}
+		return onSuccess(ctx, pr.rec)
+	}
+
+	pending := map[int]*parallelRow{}
+	next := 0
+	for pr := range results {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if um.config.Unordered {
+			if err := deliver(pr); err != nil {
+				return err
+			}
+			continue
+		}
+
+		pending[pr.seq] = pr
+		for {
+			pr, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if err := deliver(pr); err != nil {
+				return err
+			}
+		}
+	}
+	return ctx.Err()
+}
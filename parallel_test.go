@@ -0,0 +1,236 @@
+package commando
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type parallelSample struct {
+	ID   int    `csv:"id"`
+	Name string `csv:"name"`
+}
+
+// parallelCSV returns a file of n rows, where every tenth row has an
+// invalid id.
+func parallelCSV(n int) string {
+	var b strings.Builder
+	b.WriteString("id,name\n")
+	for i := 1; i <= n; i++ {
+		if i%10 == 0 {
+			fmt.Fprintf(&b, "x%d,name %d\n", i, i)
+		} else {
+			fmt.Fprintf(&b, "%d,name %d\n", i, i)
+		}
+	}
+	return b.String()
+}
+
+func readAllWithErrors(t *testing.T, c *Config, contents string) ([]parallelSample, []string) {
+	reader := csv.NewReader(strings.NewReader(contents))
+	reader.ReuseRecord = true
+	um, err := c.NewUnmarshaller(reader)
+	require.NoError(t, err)
+
+	var out []parallelSample
+	var errs []string
+	err = um.ReadAllCallback(context.Background(), func(_ context.Context, rec interface{}) error {
+		out = append(out, rec.(parallelSample))
+		return nil
+	}, func(_ context.Context, err error) error {
+		errs = append(errs, err.Error())
+		return nil
+	})
+	require.NoError(t, err)
+	return out, errs
+}
+
+func Test_ReadAllCallback_Parallel(t *testing.T) {
+	t.Parallel()
+
+	contents := parallelCSV(1000)
+	expected, expectedErrs := readAllWithErrors(t, &Config{Holder: parallelSample{}}, contents)
+	require.Len(t, expected, 900)
+	require.Len(t, expectedErrs, 100)
+	assert.Contains(t, expectedErrs[0], "line 11")
+
+	out, errs := readAllWithErrors(t, &Config{Holder: parallelSample{}, Workers: 4}, contents)
+	assert.Equal(t, expected, out)
+	assert.Equal(t, expectedErrs, errs)
+
+	out, errs = readAllWithErrors(t, &Config{Holder: parallelSample{}, Workers: 4, Unordered: true}, contents)
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	sort.Strings(errs)
+	sort.Strings(expectedErrs)
+	assert.Equal(t, expected, out)
+	assert.Equal(t, expectedErrs, errs)
+}
+
+func Test_ReadAllCallback_ParallelStop(t *testing.T) {
+	t.Parallel()
+
+	stop := errors.New("stop")
+	contents := parallelCSV(1000)
+
+	c := &Config{Holder: parallelSample{}, Workers: 4}
+	um, err := c.NewUnmarshaller(csv.NewReader(strings.NewReader(contents)))
+	require.NoError(t, err)
+
+	n := 0
+	err = um.ReadAllCallback(context.Background(), func(_ context.Context, rec interface{}) error {
+		n++
+		if n == 5 {
+			return stop
+		}
+		return nil
+	}, StopOnError)
+	require.Equal(t, stop, err)
+	assert.Equal(t, 5, n)
+	// Rows read ahead of the stop aren't counted.
+	assert.Equal(t, 5, um.Stats().Read)
+	assert.Equal(t, 5, um.Stats().Decoded)
+	assert.Equal(t, 4, um.LastRow().Index)
+	assert.Equal(t, []string{"5", "name 5"}, um.LastRow().Row)
+
+	// The first error stops processing, just like the sequential path.
+	um, err = c.NewUnmarshaller(csv.NewReader(strings.NewReader(contents)))
+	require.NoError(t, err)
+	_, err = um.ReadAll(context.Background(), StopOnError)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 11")
+	assert.Equal(t, 10, um.Stats().Read)
+	assert.Equal(t, 1, um.Stats().Rejected)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	um, err = c.NewUnmarshaller(csv.NewReader(strings.NewReader(contents)))
+	require.NoError(t, err)
+	err = um.ReadAllCallback(ctx, func(_ context.Context, rec interface{}) error {
+		cancel()
+		return nil
+	}, StopOnError)
+	require.Equal(t, context.Canceled, err)
+}
+
+func Test_ReadAllCallback_ParallelCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	var b strings.Builder
+	b.WriteString("id,name\n")
+	for i := 1; i <= 1000; i++ {
+		fmt.Fprintf(&b, "%d,name %d\n", i, i)
+	}
+	contents := b.String()
+	stop := errors.New("stop")
+
+	for _, unordered := range []bool{false, true} {
+		c := &Config{Holder: parallelSample{}, Workers: 4, Unordered: unordered}
+		um, err := c.NewUnmarshaller(csv.NewReader(strings.NewReader(contents)))
+		require.NoError(t, err)
+
+		seen := map[int]int{}
+		err = um.ReadAllCallback(context.Background(), func(_ context.Context, rec interface{}) error {
+			seen[rec.(parallelSample).ID]++
+			if len(seen) == 10 {
+				return stop
+			}
+			return nil
+		}, StopOnError)
+		require.Equal(t, stop, err)
+
+		checkpoint, err := um.Checkpoint()
+		require.NoError(t, err)
+		resumed, err := c.ResumeUnmarshaller(strings.NewReader(contents), checkpoint, nil)
+		require.NoError(t, err)
+		err = resumed.ReadAllCallback(context.Background(), func(_ context.Context, rec interface{}) error {
+			seen[rec.(parallelSample).ID]++
+			return nil
+		}, StopOnError)
+		require.NoError(t, err)
+
+		// Every row is delivered, and only once unless rows were
+		// delivered out of order before the stop.
+		var missing, repeated []int
+		for id := 1; id <= 1000; id++ {
+			if seen[id] == 0 {
+				missing = append(missing, id)
+			} else if seen[id] > 1 {
+				repeated = append(repeated, id)
+			}
+		}
+		assert.Empty(t, missing, "unordered: %v", unordered)
+		if !unordered {
+			assert.Empty(t, repeated)
+		}
+	}
+}
+
+type releaseKey struct{}
+
+// slowSample blocks unmarshalling its first row until the channel in
+// its context is closed.
+type slowSample struct {
+	ID int `csv:"id"`
+}
+
+func (s *slowSample) AfterUnmarshalCSV(ctx context.Context) error {
+	if s.ID == 1 {
+		<-ctx.Value(releaseKey{}).(chan struct{})
+	}
+	return nil
+}
+
+// countingReader counts the records read from Reader.
+type countingReader struct {
+	Reader
+	reads int32
+}
+
+func (r *countingReader) Read() ([]string, error) {
+	atomic.AddInt32(&r.reads, 1)
+	return r.Reader.Read()
+}
+
+func Test_ReadAllCallback_ParallelBackpressure(t *testing.T) {
+	t.Parallel()
+
+	var b strings.Builder
+	b.WriteString("id\n")
+	for i := 1; i <= 100; i++ {
+		fmt.Fprintf(&b, "%d\n", i)
+	}
+	reader := &countingReader{Reader: csv.NewReader(strings.NewReader(b.String()))}
+	um, err := (&Config{Holder: slowSample{}, Workers: 2}).NewUnmarshaller(reader)
+	require.NoError(t, err)
+
+	release := make(chan struct{})
+	ctx := context.WithValue(context.Background(), releaseKey{}, release)
+	var ids []int
+	done := make(chan error)
+	go func() {
+		done <- um.ReadAllCallback(ctx, func(_ context.Context, rec interface{}) error {
+			ids = append(ids, rec.(slowSample).ID)
+			return nil
+		}, StopOnError)
+	}()
+
+	// While the first row is stuck, no more than twice as many rows
+	// as workers are read, besides the header.
+	time.Sleep(50 * time.Millisecond)
+	assert.LessOrEqual(t, int(atomic.LoadInt32(&reader.reads)), 1+2*2)
+
+	close(release)
+	require.NoError(t, <-done)
+	require.Len(t, ids, 100)
+	for i, id := range ids {
+		assert.Equal(t, i+1, id)
+	}
+}
@@ -0,0 +1,121 @@
+package commando
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+)
+
+// PositionedReader is implemented by Readers which report where their
+// records are in the file, like csv.Reader.  Errors and RowMetas for
+// rows read from a PositionedReader have exact lines and columns,
+// even when quoted values span several lines.
+type PositionedReader interface {
+	Reader
+
+	// FieldPos returns the line and column, counting from 1, at
+	// which the field with the given index of the record most
+	// recently read starts.
+	FieldPos(field int) (line, column int)
+
+	// InputOffset returns the byte offset of the end of the record
+	// most recently read, or -1 if the Reader has no byte offsets.
+	InputOffset() int64
+}
+
+// csv.Reader reports positions since Go 1.19, which go.mod requires.
+var _ PositionedReader = (*csv.Reader)(nil)
+
+// PositionError is the error for a row which couldn't be
+// unmarshalled, with where in the file the problem is.
+type PositionError struct {
+	// Line is the line of the value which couldn't be unmarshalled,
+	// or of the start of the row.
+	Line int
+
+	// Column is the column of the value which couldn't be
+	// unmarshalled, counting bytes from 1, or 0 if it's unknown.
+	Column int
+
+	// StartLine is the line the row starts on.
+	StartLine int
+
+	// Index is the index of the row among the file's records,
+	// counting from 0 and not counting the header.
+	Index int
+
+	// Row is the row which couldn't be unmarshalled.  If the Reader
+	// reuses its records, it's only valid until the next row is read.
+	Row []string
+
+	Err error
+}
+
+func (e *PositionError) Error() string {
+	if e.Column > 0 {
+		return fmt.Sprintf("on line %d, column %d: %v", e.Line, e.Column, e.Err)
+	}
+	return fmt.Sprintf("on line %d: %v", e.Line, e.Err)
+}
+
+func (e *PositionError) Unwrap() error {
+	return e.Err
+}
+
+// ColumnError is an error about the value at Index in a row.
+// RecordUnmarshallers can return one so the error is reported with
+// the value's position.
+type ColumnError struct {
+	Index int
+	Err   error
+}
+
+func (e *ColumnError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ColumnError) Unwrap() error {
+	return e.Err
+}
+
+// fieldPosition is the line and column of a field.
+type fieldPosition struct {
+	line, column int
+}
+
+// fieldPositions returns the positions of the fields of the row most
+// recently read, or nil if the Reader doesn't report them.
+func (um *Unmarshaller) fieldPositions(row []string) []fieldPosition {
+	pr, ok := um.reader.(PositionedReader)
+	if !ok {
+		return nil
+	}
+	fields := make([]fieldPosition, len(row))
+	for j := range row {
+		fields[j].line, fields[j].column = pr.FieldPos(j)
+	}
+	return fields
+}
+
+// positionError wraps err, which occurred in row, read from line, in
+// a PositionError.  index is the row's index.  fields are the positions of the row's fields;
+// if they're nil, the Reader is asked, so it must not have read
+// another row since.
+func (um *Unmarshaller) positionError(err error, row []string, index, line int, fields []fieldPosition) error {
+	if err == nil {
+		return nil
+	}
+	pe := &PositionError{Line: line, StartLine: line, Index: index, Row: row, Err: err}
+	var ce *ColumnError
+	if !errors.As(err, &ce) || ce.Index < 0 {
+		return pe
+	}
+	if fields != nil {
+		if ce.Index < len(fields) {
+			pe.Line, pe.Column = fields[ce.Index].line, fields[ce.Index].column
+		}
+	} else if pr, ok := um.reader.(PositionedReader); ok && ce.Index < len(row) {
+		pe.Line, pe.Column = pr.FieldPos(ce.Index)
+	}
+	return pe
+}
@@ -0,0 +1,88 @@
+package commando
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const positionCSV = "name,age\n\"multi\nline\",1\nbob,x\n"
+
+func TestPositionError_CSVReader(t *testing.T) {
+	t.Parallel()
+
+	for _, workers := range []int{0, 2} {
+		c := &Config{Holder: metaStruct{}, Workers: workers}
+		um, err := c.NewUnmarshaller(csv.NewReader(strings.NewReader(positionCSV)))
+		require.NoError(t, err)
+
+		var errs []error
+		_, err = um.ReadAll(context.Background(), func(_ context.Context, err error) error {
+			errs = append(errs, err)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Len(t, errs, 1)
+		assert.EqualError(t, errs[0], `on line 4, column 5: cannot assign field "age" at index 1 through index chain [1]: strconv.ParseInt: parsing "x": invalid syntax`)
+
+		var pe *PositionError
+		require.True(t, errors.As(errs[0], &pe))
+		assert.Equal(t, 4, pe.Line)
+		assert.Equal(t, 5, pe.Column)
+	}
+}
+
+func TestPositionError_RowError(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{Holder: metaStruct{}, RaggedRows: RaggedError}
+	reader := csv.NewReader(strings.NewReader("name,age\n\"a\nb\",1\n\"c\nd\"\n"))
+	reader.FieldsPerRecord = -1
+	um, err := c.NewUnmarshaller(reader)
+	require.NoError(t, err)
+
+	require.NoError(t, um.ReadInto(&metaStruct{}))
+	err = um.ReadInto(&metaStruct{})
+	assert.EqualError(t, err, "on line 4: wrong number of columns: row has 1, expected 2")
+}
+
+// offsetReader reports positions as if each row were on its own line,
+// ten lines apart, with each field ten columns wide.
+type offsetReader struct {
+	rowsReader
+	line int
+}
+
+func (r *offsetReader) Read() ([]string, error) {
+	r.line += 10
+	return r.rowsReader.Read()
+}
+
+func (r *offsetReader) FieldPos(field int) (int, int) {
+	return r.line, 10*field + 1
+}
+
+func (r *offsetReader) InputOffset() int64 {
+	return 0
+}
+
+func TestPositionError_PositionedReader(t *testing.T) {
+	t.Parallel()
+
+	var _ PositionedReader = &offsetReader{}
+	var _ PositionedReader = &csv.Reader{}
+
+	um, err := NewUnmarshaller(raggedStruct{}, &offsetReader{rowsReader: rowsReader{rows: [][]string{
+		{"foo", "bar"},
+		{"a", "x"},
+	}}})
+	require.NoError(t, err)
+
+	_, err = um.Read()
+	assert.EqualError(t, err, `on line 20, column 11: cannot assign field "bar" at index 1 through index chain [1]: strconv.ParseInt: parsing "x": invalid syntax`)
+}
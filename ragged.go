@@ -0,0 +1,46 @@
+package commando
+
+import (
+	"errors"
+	"fmt"
+)
+
+// RaggedRows is how rows with more or fewer columns than the headers
+// are handled.
+type RaggedRows int
+
+const (
+	// RaggedIgnore leaves missing columns empty and ignores extra
+	// columns.
+	RaggedIgnore RaggedRows = iota
+
+	// RaggedWarn reads rows like RaggedIgnore, and passes a Warning
+	// to the WarningHandler for each of them.
+	RaggedWarn
+
+	// RaggedError rejects rows with an error wrapping
+	// ErrColumnCount.
+	RaggedError
+)
+
+// ErrColumnCount is the error for a row with more or fewer columns
+// than the headers, when RaggedRows is RaggedError.
+var ErrColumnCount = errors.New("wrong number of columns")
+
+// checkColumnCount applies RaggedRows to row, which was read from
+// line.  Unlike csv.Reader's FieldsPerRecord, it works with any
+// Reader.
+func (vc *validConfig) checkColumnCount(row []string, line int) error {
+	if vc.RaggedRows == RaggedIgnore {
+		return nil
+	}
+	expected := len(vc.fileHeaders())
+	if len(row) == expected {
+		return nil
+	}
+	if vc.RaggedRows == RaggedError {
+		return fmt.Errorf("%w: row has %d, expected %d", ErrColumnCount, len(row), expected)
+	}
+	vc.warn(Warning{Line: line, Message: fmt.Sprintf("row has %d columns, expected %d", len(row), expected)})
+	return nil
+}
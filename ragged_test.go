@@ -0,0 +1,85 @@
+package commando
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type raggedStruct struct {
+	Foo string `csv:"foo"`
+	Bar int    `csv:"bar"`
+}
+
+// rowsReader is a Reader which isn't a csv.Reader, so nothing checks
+// the number of columns for us.
+type rowsReader struct {
+	rows [][]string
+}
+
+func (r *rowsReader) Read() ([]string, error) {
+	if len(r.rows) == 0 {
+		return nil, io.EOF
+	}
+	row := r.rows[0]
+	r.rows = r.rows[1:]
+	return row, nil
+}
+
+func raggedRows() *rowsReader {
+	return &rowsReader{rows: [][]string{
+		{"foo", "bar"},
+		{"a", "1"},
+		{"b"},
+		{"c", "3", "extra"},
+	}}
+}
+
+func TestRaggedRows_Error(t *testing.T) {
+	t.Parallel()
+
+	um, err := (&Config{Holder: raggedStruct{}, RaggedRows: RaggedError}).NewUnmarshaller(raggedRows())
+	require.NoError(t, err)
+
+	var errs []error
+	out, err := um.ReadAll(context.Background(), func(_ context.Context, err error) error {
+		errs = append(errs, err)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []raggedStruct{{Foo: "a", Bar: 1}}, out)
+	require.Len(t, errs, 2)
+	assert.EqualError(t, errs[0], "on line 3: wrong number of columns: row has 1, expected 2")
+	assert.EqualError(t, errs[1], "on line 4: wrong number of columns: row has 3, expected 2")
+	assert.True(t, errors.Is(errs[0], ErrColumnCount))
+
+	um, err = (&Config{Holder: raggedStruct{}, RaggedRows: RaggedError}).NewUnmarshaller(raggedRows())
+	require.NoError(t, err)
+	require.NoError(t, um.ReadInto(&raggedStruct{}))
+	assert.EqualError(t, um.ReadInto(&raggedStruct{}), "on line 3: wrong number of columns: row has 1, expected 2")
+}
+
+func TestRaggedRows_Warn(t *testing.T) {
+	t.Parallel()
+
+	var warnings []Warning
+	c := &Config{Holder: raggedStruct{}, RaggedRows: RaggedWarn, WarningHandler: func(w Warning) {
+		warnings = append(warnings, w)
+	}}
+	um, err := c.NewUnmarshaller(raggedRows())
+	require.NoError(t, err)
+
+	out, err := um.ReadAll(context.Background(), func(_ context.Context, err error) error {
+		return err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []raggedStruct{{Foo: "a", Bar: 1}, {Foo: "b"}, {Foo: "c", Bar: 3}}, out)
+	assert.Equal(t, []Warning{
+		{Line: 3, Message: "row has 1 columns, expected 2"},
+		{Line: 4, Message: "row has 3 columns, expected 2"},
+	}, warnings)
+}
@@ -0,0 +1,302 @@
+package commando
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ErrColumnNotFound is returned by Record's getters when a record has
+// no column with the requested name.
+var ErrColumnNotFound = errors.New("column not found")
+
+// Record is a row of a CSV file whose layout isn't known at compile
+// time.  An Unmarshaller whose Holder is a Record, *Record or
+// map[string]string returns one per row, and a Marshaller whose
+// Holder is one of those types writes them.
+type Record struct {
+	// Headers are the names of the columns: in file order, or the
+	// Schema's column names in Schema order if the record was read
+	// with a Schema.  They're shared by every Record read by the same
+	// Unmarshaller, and must not be modified.  RowMeta.Row, from
+	// ReadWithMeta or LastRow, has the row as it was in the file.
+	Headers []string
+
+	// Values are the values of the columns, in the same order as
+	// Headers.
+	Values []string
+
+	// typed are the typed values of the columns, if the record was
+	// read with a Schema.
+	typed []interface{}
+}
+
+// Index returns the position of the column called name, or -1 if
+// there is none.  Like struct tags, name matches headers with
+// surrounding whitespace.
+func (r Record) Index(name string) int {
+	trimmed := -1
+	for i, header := range r.Headers {
+		if header == name {
+			return i
+		}
+		if trimmed < 0 && strings.TrimSpace(header) == name {
+			trimmed = i
+		}
+	}
+	return trimmed
+}
+
+// Get returns the value of the column called name, and whether the
+// record has that column.
+func (r Record) Get(name string) (string, bool) {
+	i := r.Index(name)
+	if i < 0 || i >= len(r.Values) {
+		return "", false
+	}
+	return r.Values[i], true
+}
+
+// Value returns the typed value of the column called name, if the
+// record was read with a Schema: a string, int64, float64, bool,
+// time.Time, []interface{} or map[string]interface{}, or nil if the
+// value is empty.  For records read without
+// a Schema, it returns the same string as Get.  It returns nil if the
+// record has no column called name.
+func (r Record) Value(name string) interface{} {
+	i := r.Index(name)
+	switch {
+	case i < 0 || i >= len(r.Values):
+		return nil
+	case r.typed != nil:
+		return r.typed[i]
+	}
+	return r.Values[i]
+}
+
+// Set sets the value of the column called name, adding the column if
+// it doesn't exist.  Since Headers may be shared with other records,
+// adding a column copies them first.
+func (r *Record) Set(name, value string) {
+	i := r.Index(name)
+	if i < 0 {
+		r.Headers = append(r.Headers[:len(r.Headers):len(r.Headers)], name)
+		i = len(r.Headers) - 1
+	}
+	for len(r.Values) <= i {
+		r.Values = append(r.Values, "")
+	}
+	r.Values[i] = value
+	// The typed values no longer match.
+	r.typed = nil
+}
+
+// Map returns the record's columns as a map.  If a header is
+// repeated, the first column with that header wins.
+func (r Record) Map() map[string]string {
+	m := make(map[string]string, len(r.Headers))
+	for i, header := range r.Headers {
+		if _, ok := m[header]; ok || i >= len(r.Values) {
+			continue
+		}
+		m[header] = r.Values[i]
+	}
+	return m
+}
+
+// lookup returns the value of the column called name, or an error if
+// there is none.
+func (r Record) lookup(name string) (string, error) {
+	value, ok := r.Get(name)
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrColumnNotFound, name)
+	}
+	return value, nil
+}
+
+// Bool returns the value of the column called name, converted the
+// same way as bool fields.
+func (r Record) Bool(name string) (bool, error) {
+	value, err := r.lookup(name)
+	if err != nil {
+		return false, err
+	}
+	b, err := toBool(value)
+	if err != nil {
+		return false, fmt.Errorf("column %q: %w", name, err)
+	}
+	return b, nil
+}
+
+// Int returns the value of the column called name, converted the
+// same way as int fields.
+func (r Record) Int(name string) (int64, error) {
+	value, err := r.lookup(name)
+	if err != nil {
+		return 0, err
+	}
+	i, err := toInt(value)
+	if err != nil {
+		return 0, fmt.Errorf("column %q: %w", name, err)
+	}
+	return i, nil
+}
+
+// Uint returns the value of the column called name, converted the
+// same way as uint fields.
+func (r Record) Uint(name string) (uint64, error) {
+	value, err := r.lookup(name)
+	if err != nil {
+		return 0, err
+	}
+	u, err := toUint(value)
+	if err != nil {
+		return 0, fmt.Errorf("column %q: %w", name, err)
+	}
+	return u, nil
+}
+
+// Float returns the value of the column called name, converted the
+// same way as float fields.
+func (r Record) Float(name string) (float64, error) {
+	value, err := r.lookup(name)
+	if err != nil {
+		return 0, err
+	}
+	f, err := toFloat(value)
+	if err != nil {
+		return 0, fmt.Errorf("column %q: %w", name, err)
+	}
+	return f, nil
+}
+
+// Time returns the value of the column called name, parsed with
+// layout.  Like the numeric getters, an empty value is the zero
+// time.
+func (r Record) Time(name, layout string) (time.Time, error) {
+	value, err := r.lookup(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("column %q: %w", name, err)
+	}
+	return t, nil
+}
+
+// --------------------------------------------------------------------------
+// Dynamic holders
+
+var (
+	recordType    = reflect.TypeOf(Record{})
+	stringMapType = reflect.TypeOf(map[string]string(nil))
+)
+
+// isDynamic reports whether holders of type t are decoded as Records
+// or maps rather than structs.
+func isDynamic(t reflect.Type) bool {
+	if t == nil {
+		return false
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t == recordType || t == stringMapType
+}
+
+// validateDynamic is validate for Record and map holders, which
+// accept any headers.  Records and maps are keyed by the headers'
+// canonical keys.
+func (c *Config) validateDynamic(headers []string) (*validConfig, error) {
+	headers = c.headerKeys(headers)
+	if c.FailIfDoubleHeaderNames {
+		if err := maybeDoubleHeaderNames(headers); err != nil {
+			return nil, err
+		}
+	}
+	return &validConfig{
+		Config:  *c,
+		outType: reflect.TypeOf(c.Holder),
+		headers: headers,
+		dynamic: true,
+	}, nil
+}
+
+// decodeDynamic sets out, an addressable Record or map[string]string,
+// from a CSV row.  It reuses out's storage, if any.
+func (vc *validConfig) decodeDynamic(out reflect.Value, row []string) error {
+	switch out.Type() {
+	case recordType:
+		rec := out.Addr().Interface().(*Record)
+		rec.Headers = vc.headers
+		if vc.schema != nil {
+			values, typed, err := vc.schema.decode(row, rec.Values[:0], rec.typed[:0])
+			if err != nil {
+				return err
+			}
+			rec.Values, rec.typed = values, typed
+			return nil
+		}
+		rec.Values = append(rec.Values[:0], row...)
+		rec.typed = nil
+	case stringMapType:
+		if vc.schema != nil {
+			values, _, err := vc.schema.decode(row, nil, nil)
+			if err != nil {
+				return err
+			}
+			row = values
+		}
+		m := out.Interface().(map[string]string)
+		if m == nil {
+			m = make(map[string]string, len(vc.headers))
+			out.Set(reflect.ValueOf(m))
+		}
+		for k := range m {
+			delete(m, k)
+		}
+		for i, header := range vc.headers {
+			if _, ok := m[header]; ok || i >= len(row) {
+				continue
+			}
+			m[header] = row[i]
+		}
+	}
+	return nil
+}
+
+// encodeDynamic returns the row for record, a Record, *Record or
+// map[string]string, with a column for each of vc's headers.
+func (vc *validConfig) encodeDynamic(record interface{}) ([]string, error) {
+	row := make([]string, len(vc.headers))
+	switch r := record.(type) {
+	case Record:
+		for i, header := range vc.headers {
+			row[i], _ = r.Get(header)
+		}
+	case *Record:
+		if r == nil {
+			return nil, fmt.Errorf("Expected Record or map[string]string, but got nil %T", record)
+		}
+		return vc.encodeDynamic(*r)
+	case map[string]string:
+		for i, header := range vc.headers {
+			row[i] = r[header]
+		}
+	default:
+		return nil, fmt.Errorf("Expected Record or map[string]string, but got %T", record)
+	}
+	if vc.schema != nil {
+		if err := vc.schema.validate(row); err != nil {
+			return nil, err
+		}
+	}
+	return row, nil
+}
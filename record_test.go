@@ -0,0 +1,175 @@
+package commando
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const recordCSV = `sku, qty,price,paid,date
+abc,3,1.5,yes,2020-01-02
+def,,,no,
+`
+
+func TestRecord_Getters(t *testing.T) {
+	t.Parallel()
+
+	um, err := NewUnmarshaller(Record{}, csv.NewReader(strings.NewReader(recordCSV)))
+	require.NoError(t, err)
+
+	out, err := um.Read()
+	require.NoError(t, err)
+	rec := out.(Record)
+	assert.Equal(t, []string{"sku", " qty", "price", "paid", "date"}, rec.Headers)
+	assert.Equal(t, []string{"abc", "3", "1.5", "yes", "2020-01-02"}, rec.Values)
+
+	sku, ok := rec.Get("sku")
+	assert.True(t, ok)
+	assert.Equal(t, "abc", sku)
+
+	qty, err := rec.Int("qty")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), qty)
+
+	uqty, err := rec.Uint("qty")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), uqty)
+
+	price, err := rec.Float("price")
+	require.NoError(t, err)
+	assert.Equal(t, 1.5, price)
+
+	paid, err := rec.Bool("paid")
+	require.NoError(t, err)
+	assert.True(t, paid)
+
+	date, err := rec.Time("date", "2006-01-02")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), date)
+
+	_, err = rec.Int("sku")
+	assert.EqualError(t, err, `column "sku": strconv.ParseInt: parsing "abc": invalid syntax`)
+
+	_, err = rec.Time("sku", "2006-01-02")
+	assert.Error(t, err)
+
+	_, err = rec.Int("missing")
+	assert.True(t, errors.Is(err, ErrColumnNotFound))
+	assert.EqualError(t, err, `column not found: "missing"`)
+
+	out, err = um.Read()
+	require.NoError(t, err)
+	rec = out.(Record)
+
+	qty, err = rec.Int("qty")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), qty)
+
+	date, err = rec.Time("date", "2006-01-02")
+	require.NoError(t, err)
+	assert.True(t, date.IsZero())
+}
+
+func TestRecord_Set(t *testing.T) {
+	t.Parallel()
+
+	headers := []string{"a", "b"}
+	rec := Record{Headers: headers, Values: []string{"1"}}
+
+	rec.Set("b", "2")
+	assert.Equal(t, []string{"1", "2"}, rec.Values)
+
+	rec.Set("c", "3")
+	assert.Equal(t, []string{"a", "b", "c"}, rec.Headers)
+	assert.Equal(t, []string{"1", "2", "3"}, rec.Values)
+	assert.Equal(t, []string{"a", "b"}, headers, "shared headers were modified")
+
+	assert.Equal(t, map[string]string{"a": "1", "b": "2", "c": "3"}, rec.Map())
+}
+
+func TestUnmarshaller_Map(t *testing.T) {
+	t.Parallel()
+
+	csvText := "a,b,a\n1,2,3\n4\n"
+	um, err := NewUnmarshaller(map[string]string{}, csv.NewReader(strings.NewReader(csvText)))
+	require.NoError(t, err)
+	um.reader.(*csv.Reader).FieldsPerRecord = -1
+
+	out, err := um.ReadAll(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]string{
+		{"a": "1", "b": "2"},
+		{"a": "4"},
+	}, out)
+}
+
+func TestUnmarshaller_RecordPointer(t *testing.T) {
+	t.Parallel()
+
+	um, err := NewUnmarshaller(&Record{}, csv.NewReader(strings.NewReader(recordCSV)))
+	require.NoError(t, err)
+
+	out, err := um.Read()
+	require.NoError(t, err)
+	require.IsType(t, &Record{}, out)
+	assert.Equal(t, "abc", out.(*Record).Values[0])
+}
+
+func TestUnmarshaller_DynamicDoubleHeaders(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{Holder: Record{}, FailIfDoubleHeaderNames: true}
+	_, err := c.NewUnmarshaller(csv.NewReader(strings.NewReader("a,a\n")))
+	assert.EqualError(t, err, "repeated header name: a")
+}
+
+func TestReadInto_Record(t *testing.T) {
+	t.Parallel()
+
+	um, err := NewUnmarshaller(Record{}, csv.NewReader(strings.NewReader(recordCSV)))
+	require.NoError(t, err)
+
+	var rec Record
+	require.NoError(t, um.ReadInto(&rec))
+	values := rec.Values
+	require.NoError(t, um.ReadInto(&rec))
+	assert.Equal(t, []string{"def", "", "", "no", ""}, rec.Values)
+	assert.Equal(t, &values[0], &rec.Values[0], "Values weren't reused")
+
+	m := map[string]string{"stale": "x"}
+	um, err = NewUnmarshaller(map[string]string{}, csv.NewReader(strings.NewReader(recordCSV)))
+	require.NoError(t, err)
+	require.NoError(t, um.ReadInto(&m))
+	assert.Equal(t, map[string]string{"sku": "abc", " qty": "3", "price": "1.5", "paid": "yes", "date": "2020-01-02"}, m)
+}
+
+func TestMarshaller_Dynamic(t *testing.T) {
+	t.Parallel()
+
+	out := new(bytes.Buffer)
+	c := &Config{Holder: Record{}, Headers: []string{"a", "b"}}
+	m, err := c.NewMarshaller(csv.NewWriter(out))
+	require.NoError(t, err)
+
+	require.NoError(t, m.Write(Record{Headers: []string{"b", "a"}, Values: []string{"2", "1"}}))
+	require.NoError(t, m.Write(&Record{Headers: []string{"a"}, Values: []string{"3"}}))
+	require.NoError(t, m.WriteAll([]map[string]string{{"b": "4", "c": "x"}}))
+	assert.EqualError(t, m.Write(42), "Expected Record or map[string]string, but got int")
+	require.NoError(t, m.Flush())
+
+	assert.Equal(t, "a,b\n1,2\n3,\n,4\n", out.String())
+}
+
+func TestMarshaller_DynamicNoHeaders(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewMarshaller(map[string]string{}, csv.NewWriter(new(bytes.Buffer)))
+	assert.EqualError(t, err, "Headers must be set to marshal map[string]string")
+}
@@ -1,9 +1,12 @@
 package commando
 
 import (
+	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 	"sync"
+	"unicode/utf8"
 )
 
 const (
@@ -13,6 +16,10 @@ const (
 	// TagSeparator defines seperator string for multiple csv tags in
 	// struct fields
 	tagSeparator = ","
+
+	// tagOptionSeparator separates the name and value of a tag option,
+	// such as width=10
+	tagOptionSeparator = "="
 )
 
 // --------------------------------------------------------------------------
@@ -34,9 +41,82 @@ func (si *structInfo) headers() []string {
 // Each IndexChain element before the last is the index of an the embedded struct field
 // that defines Key as a tag
 type fieldInfo struct {
-	keys       []string
-	omitEmpty  bool
+	keys      []string
+	omitEmpty bool
+	// required is only enforced with Config.EnforceRequired;
+	// otherwise it just describes the field, for DescribeSchema.
+	required   bool
 	IndexChain []int
+
+	// decode and encode convert the field from and to its CSV
+	// representation.
+	decode decodeFunc
+	encode encodeFunc
+
+	// width, align and pad describe the field's column in a
+	// fixed-width layout.
+	width int
+	align alignment
+	pad   rune
+
+	// enum maps the field's labels to its values, if it has any.
+	enum *enum
+
+	// rules are the validation rules the field's values must
+	// satisfy.
+	rules []*rule
+}
+
+// alignment is the side of a fixed-width column a value is aligned
+// to.
+type alignment int
+
+const (
+	alignLeft alignment = iota
+	alignRight
+)
+
+// setOption applies a name=value tag option to a field of type t.
+func (f *fieldInfo) setOption(name, value string, t reflect.Type) error {
+	if r, ok, err := compileRule(name, value, t); ok {
+		if err != nil {
+			return err
+		}
+		f.rules = append(f.rules, r)
+		return nil
+	}
+
+	switch name {
+	case "width":
+		width, err := strconv.Atoi(value)
+		if err != nil || width <= 0 {
+			return fmt.Errorf("invalid width %q", value)
+		}
+		f.width = width
+	case "align":
+		switch value {
+		case "left":
+			f.align = alignLeft
+		case "right":
+			f.align = alignRight
+		default:
+			return fmt.Errorf("invalid align %q", value)
+		}
+	case "enum":
+		e, err := parseEnum(value)
+		if err != nil {
+			return err
+		}
+		f.enum = e
+	case "pad":
+		if utf8.RuneCountInString(value) != 1 {
+			return fmt.Errorf("invalid pad %q, must be a single character", value)
+		}
+		f.pad, _ = utf8.DecodeRuneInString(value)
+	default:
+		return fmt.Errorf("unknown option %q", name)
+	}
+	return nil
 }
 
 func (f fieldInfo) getFirstKey() string {
@@ -56,20 +136,36 @@ var structInfoCache sync.Map
 var structMap = make(map[reflect.Type]*structInfo)
 var structMapMutex sync.RWMutex
 
-func getStructInfo(rType reflect.Type) *structInfo {
+func getStructInfo(rType reflect.Type) (*structInfo, error) {
 	stInfo, ok := structInfoCache.Load(rType)
 	if ok {
-		return stInfo.(*structInfo)
+		return stInfo.(*structInfo), nil
 	}
 
-	fieldsList := getFieldInfos(rType, []int{})
+	fieldsList, err := getFieldInfos(rType, []int{})
+	if err != nil {
+		return nil, err
+	}
 	stInfo = &structInfo{fieldsList}
 	structInfoCache.Store(rType, stInfo)
 
-	return stInfo.(*structInfo)
+	return stInfo.(*structInfo), nil
 }
 
-func getFieldInfos(rType reflect.Type, parentIndexChain []int) []fieldInfo {
+// getHolderStructInfo returns the structInfo of holder, which must be
+// a struct or a pointer to one.
+func getHolderStructInfo(holder interface{}) (*structInfo, error) {
+	concreteType := reflect.TypeOf(holder)
+	if concreteType.Kind() == reflect.Ptr {
+		concreteType = concreteType.Elem()
+	}
+	if err := ensureOutInnerType(concreteType); err != nil {
+		return nil, err
+	}
+	return getStructInfo(concreteType) // Get struct info to get CSV annotations.
+}
+
+func getFieldInfos(rType reflect.Type, parentIndexChain []int) ([]fieldInfo, error) {
 	fieldsCount := rType.NumField()
 	fieldsList := make([]fieldInfo, 0, fieldsCount)
 	for i := 0; i < fieldsCount; i++ {
@@ -87,7 +183,11 @@ func getFieldInfos(rType reflect.Type, parentIndexChain []int) []fieldInfo {
 			// unless it implements marshalText or marshalCSV. Structs that implement this
 			// should result in one value and not have their fields exposed
 			if !(canMarshal(field.Type.Elem())) {
-				fieldsList = append(fieldsList, getFieldInfos(field.Type.Elem(), indexChain)...)
+				embedded, err := getFieldInfos(field.Type.Elem(), indexChain)
+				if err != nil {
+					return nil, err
+				}
+				fieldsList = append(fieldsList, embedded...)
 			}
 		}
 		// if the field is a struct, create a fieldInfo for each of its fields
@@ -95,7 +195,11 @@ func getFieldInfos(rType reflect.Type, parentIndexChain []int) []fieldInfo {
 			// unless it implements marshalText or marshalCSV. Structs that implement this
 			// should result in one value and not have their fields exposed
 			if !(canMarshal(field.Type)) {
-				fieldsList = append(fieldsList, getFieldInfos(field.Type, indexChain)...)
+				embedded, err := getFieldInfos(field.Type, indexChain)
+				if err != nil {
+					return nil, err
+				}
+				fieldsList = append(fieldsList, embedded...)
 			}
 		}
 
@@ -104,13 +208,27 @@ func getFieldInfos(rType reflect.Type, parentIndexChain []int) []fieldInfo {
 			continue
 		}
 
-		fieldInfo := fieldInfo{IndexChain: indexChain}
+		fieldInfo := fieldInfo{IndexChain: indexChain, pad: ' '}
 		fieldTag := field.Tag.Get(tagName)
-		fieldTags := strings.Split(fieldTag, tagSeparator)
+		fieldTags := splitTag(fieldTag)
 		filteredTags := []string{}
-		for _, fieldTagEntry := range fieldTags {
+		hasOption := false
+		// The first entry is always a key, so that a column can be
+		// called "required".
+		for k, fieldTagEntry := range fieldTags {
 			if fieldTagEntry == "omitempty" {
 				fieldInfo.omitEmpty = true
+			} else if k > 0 && fieldTagEntry == "required" {
+				fieldInfo.required = true
+			} else if i := strings.Index(fieldTagEntry, tagOptionSeparator); i >= 0 {
+				if err := fieldInfo.setOption(fieldTagEntry[:i], fieldTagEntry[i+1:], field.Type); err != nil {
+					return nil, fmt.Errorf("field %s: %v", field.Name, err)
+				}
+				hasOption = true
+			} else if hasOption {
+				// Most likely part of an option value which
+				// contains a comma.
+				return nil, fmt.Errorf("field %s: key %q follows an option, keys must come first", field.Name, fieldTagEntry)
 			} else {
 				filteredTags = append(filteredTags, fieldTagEntry)
 			}
@@ -123,9 +241,28 @@ func getFieldInfos(rType reflect.Type, parentIndexChain []int) []fieldInfo {
 		} else {
 			fieldInfo.keys = []string{field.Name}
 		}
+		if fieldInfo.enum != nil {
+			fieldInfo.decode = enumDecoder(fieldInfo.enum, compileEnumDecoder(field.Type, fieldInfo.omitEmpty))
+			fieldInfo.encode = enumEncoder(fieldInfo.enum, compileEnumEncoder(field.Type))
+		} else {
+			fieldInfo.decode = compileDecoder(field.Type, fieldInfo.omitEmpty)
+			fieldInfo.encode = compileEncoder(field.Type)
+		}
 		fieldsList = append(fieldsList, fieldInfo)
 	}
-	return fieldsList
+	return fieldsList, nil
+}
+
+// splitTag splits a csv struct tag into its entries.  A regex option
+// runs to the end of the tag, so that its pattern may contain commas.
+func splitTag(tag string) []string {
+	entries := strings.Split(tag, tagSeparator)
+	for k, entry := range entries {
+		if strings.HasPrefix(entry, "regex"+tagOptionSeparator) {
+			return append(entries[:k], strings.Join(entries[k:], tagSeparator))
+		}
+	}
+	return entries
 }
 
 func getConcreteReflectValueAndType(in interface{}) (reflect.Value, reflect.Type) {
@@ -135,19 +272,3 @@ func getConcreteReflectValueAndType(in interface{}) (reflect.Value, reflect.Type
 	}
 	return value, value.Type()
 }
-
-func getInnerField(outInner reflect.Value, outInnerWasPointer bool, index []int) (string, error) {
-	oi := outInner
-	if outInnerWasPointer {
-		if oi.IsNil() {
-			return "", nil
-		}
-		oi = outInner.Elem()
-	}
-	// because pointers can be nil need to recurse one index at a time and perform nil check
-	if len(index) > 1 {
-		nextField := oi.Field(index[0])
-		return getInnerField(nextField, nextField.Kind() == reflect.Ptr, index[1:])
-	}
-	return getFieldAsString(oi.FieldByIndex(index))
-}
@@ -0,0 +1,155 @@
+package commando
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// --------------------------------------------------------------------------
+// Validation rules
+//
+// Tag options such as oneof=NY|CA and max=100 add rules which a
+// field's value must satisfy, beyond converting to the field's type.
+// Rules are checked against the value in the file, once all of a
+// record's fields are set, and only if the value isn't empty; use
+// required with Config.EnforceRequired to reject empty values.  A
+// regex option runs to the end of the tag, so its pattern may contain
+// commas.
+
+// ValidationError is the error for a value which breaks a rule.
+type ValidationError struct {
+	// Header is the header of the value's column.
+	Header string
+
+	// Value is the value in the file.
+	Value string
+
+	// Rule is the rule the value breaks, as written in the tag, such
+	// as "max=100".
+	Rule string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("field %q: value %q does not satisfy %s", e.Header, e.Value, e.Rule)
+}
+
+// rule is a compiled validation rule.
+type rule struct {
+	// text is the rule as written in the tag.
+	text  string
+	check func(value string) bool
+}
+
+// isNumber reports whether fields of type t hold numbers, which min
+// and max compare by value rather than by length.
+func isNumber(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// compileRule returns the rule for the tag option name=value on a
+// field of type t, or false if name isn't a rule.
+func compileRule(name, value string, t reflect.Type) (*rule, bool, error) {
+	r := &rule{text: name + tagOptionSeparator + value}
+	switch name {
+	case "oneof":
+		allowed := strings.Split(value, "|")
+		r.check = func(s string) bool {
+			for _, a := range allowed {
+				if s == a {
+					return true
+				}
+			}
+			return false
+		}
+	case "min", "max":
+		limit, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, true, fmt.Errorf("invalid %s %q", name, value)
+		}
+		// Numbers are compared by value, anything else by length.
+		measure := func(s string) (float64, bool) {
+			return float64(utf8.RuneCountInString(s)), true
+		}
+		if isNumber(t) {
+			measure = func(s string) (float64, bool) {
+				f, err := ParseFloat(s)
+				return f, err == nil
+			}
+		}
+		isMin := name == "min"
+		r.check = func(s string) bool {
+			f, ok := measure(s)
+			if !ok {
+				return false
+			}
+			if isMin {
+				return f >= limit
+			}
+			return f <= limit
+		}
+	case "len", "maxlen":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return nil, true, fmt.Errorf("invalid %s %q", name, value)
+		}
+		isLen := name == "len"
+		r.check = func(s string) bool {
+			count := utf8.RuneCountInString(s)
+			if isLen {
+				return count == n
+			}
+			return count <= n
+		}
+	case "regex":
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, true, fmt.Errorf("invalid regex %q: %v", value, err)
+		}
+		r.check = re.MatchString
+	default:
+		return nil, false, nil
+	}
+	return r, true, nil
+}
+
+// hasRules reports whether any of fields has validation rules.
+func hasRules(fields []fieldInfo) bool {
+	for _, field := range fields {
+		if len(field.rules) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRules returns a ValidationError for the first value in row
+// which breaks a rule of its field.
+func (vc *validConfig) checkRules(row []string) error {
+	if !vc.hasRules {
+		return nil
+	}
+	for j, value := range row {
+		if j >= len(vc.fieldInfoMap) || vc.fieldInfoMap[j] == nil || strings.TrimSpace(value) == "" {
+			continue
+		}
+		for _, r := range vc.fieldInfoMap[j].rules {
+			if !r.check(value) {
+				return &ColumnError{Index: j, Err: &ValidationError{Header: vc.headers[j], Value: value, Rule: r.text}}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package commando
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ruledStruct struct {
+	State string   `csv:"state,oneof=NY|CA|TX"`
+	Score int      `csv:"score,min=0,max=100"`
+	Code  string   `csv:"code,regex=^[A-Z]{3}$"`
+	Zip   string   `csv:"zip,len=5"`
+	Name  string   `csv:"name,min=2,maxlen=4"`
+	Rate  *float64 `csv:"rate,max=1.5"`
+}
+
+func TestRules(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		row    string
+		column int
+		err    string
+	}{
+		{"NY,0,ABC,10001,Ann,1.5", 0, ""},
+		{",,,,,", 0, ""},
+		{"ZZ,1,ABC,10001,Ann,", 1, `field "state": value "ZZ" does not satisfy oneof=NY|CA|TX`},
+		{"CA,-1,ABC,10001,Ann,", 4, `field "score": value "-1" does not satisfy min=0`},
+		{"CA,101,ABC,10001,Ann,", 4, `field "score": value "101" does not satisfy max=100`},
+		{"CA,1,abc,10001,Ann,", 6, `field "code": value "abc" does not satisfy regex=^[A-Z]{3}$`},
+		{"CA,1,ABC,1000,Ann,", 10, `field "zip": value "1000" does not satisfy len=5`},
+		{"CA,1,ABC,10001,A,", 16, `field "name": value "A" does not satisfy min=2`},
+		{"CA,1,ABC,10001,Émile,", 16, `field "name": value "Émile" does not satisfy maxlen=4`},
+		{"CA,1,ABC,10001,Zoé,1.6", 21, `field "rate": value "1.6" does not satisfy max=1.5`},
+		{"CA,x,abc,1,A,", 4, `cannot assign field "score" at index 1 through index chain [1]: strconv.ParseInt: parsing "x": invalid syntax`},
+	} {
+		reader := csv.NewReader(strings.NewReader("state,score,code,zip,name,rate\n" + test.row + "\n"))
+		um, err := NewUnmarshaller(&ruledStruct{}, reader)
+		require.NoError(t, err)
+		_, err = um.Read()
+		if test.err == "" {
+			assert.NoError(t, err, test.row)
+			continue
+		}
+		assert.EqualError(t, err, fmt.Sprintf("on line 2, column %d: %s", test.column, test.err), test.row)
+	}
+}
+
+func TestRules_ValidationError(t *testing.T) {
+	t.Parallel()
+
+	um, err := NewUnmarshaller(&ruledStruct{}, csv.NewReader(strings.NewReader("code,state\nABC,FL\n")))
+	require.NoError(t, err)
+	_, err = um.Read()
+	var ve *ValidationError
+	require.True(t, errors.As(err, &ve))
+	assert.Equal(t, &ValidationError{Header: "state", Value: "FL", Rule: "oneof=NY|CA|TX"}, ve)
+}
+
+type regexCommaStruct struct {
+	Code string `csv:"code,omitempty,regex=^[A-Z]{2,3}$"`
+}
+
+func TestRules_RegexComma(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		value string
+		ok    bool
+	}{
+		{"AB", true},
+		{"ABC", true},
+		{"A", false},
+		{"ABCD", false},
+	} {
+		um, err := NewUnmarshaller(&regexCommaStruct{}, csv.NewReader(strings.NewReader("code\n"+test.value+"\n")))
+		require.NoError(t, err)
+		_, err = um.Read()
+		if test.ok {
+			assert.NoError(t, err, test.value)
+		} else {
+			assert.EqualError(t, err, fmt.Sprintf(`on line 2, column 1: field "code": value %q does not satisfy regex=^[A-Z]{2,3}$`, test.value))
+		}
+	}
+}
+
+func TestRules_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		holder interface{}
+		err    string
+	}{
+		{&struct {
+			A int `csv:"a,min=x"`
+		}{}, `field A: invalid min "x"`},
+		{&struct {
+			A string `csv:"a,len=-1"`
+		}{}, `field A: invalid len "-1"`},
+		{&struct {
+			A string `csv:"a,regex=("`
+		}{}, "field A: invalid regex \"(\": error parsing regexp: missing closing ): `(`"},
+		{&struct {
+			A string `csv:"a,oneof=x,y"`
+		}{}, `field A: key "y" follows an option, keys must come first`},
+	} {
+		_, err := NewUnmarshaller(test.holder, csv.NewReader(strings.NewReader("a\n")))
+		assert.EqualError(t, err, test.err)
+	}
+}
@@ -0,0 +1,297 @@
+package commando
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ColumnType is the type of the values in a Schema column.  The names
+// are those used by Frictionless Table Schema.
+type ColumnType string
+
+const (
+	TypeString   ColumnType = "string"
+	TypeInteger  ColumnType = "integer"
+	TypeNumber   ColumnType = "number"
+	TypeBoolean  ColumnType = "boolean"
+	TypeDate     ColumnType = "date"
+	TypeDateTime ColumnType = "datetime"
+	TypeArray    ColumnType = "array"
+	TypeObject   ColumnType = "object"
+)
+
+// Default layouts of date and datetime columns without a Format.
+const (
+	DefaultDateFormat     = "2006-01-02"
+	DefaultDateTimeFormat = time.RFC3339
+)
+
+// Schema describes the layout of a CSV file at runtime, for files
+// which have no struct to hold them.  Set Config.Schema to read and
+// write Records or maps whose values are validated against it.
+type Schema struct {
+	Columns []Column
+}
+
+// Column is a column of a Schema.
+type Column struct {
+	// Name is the canonical name of the column, which is used as
+	// its header when writing, and as its key in Records and maps.
+	Name string
+
+	// Aliases are other headers the column may have when reading.
+	Aliases []string
+
+	// Type is the type of the column's values.  If unset, values
+	// are strings.
+	Type ColumnType
+
+	// Required indicates whether the column must be present in the
+	// file's headers, and must have a value in every row.
+	Required bool
+
+	// Format is the time layout of date and datetime columns.
+	Format string
+
+	// Default is used in place of an empty value.
+	Default string
+
+	// Enum, if set, lists the values the column may have.
+	Enum []string
+}
+
+// schemaColumn is a Column with its parser.
+type schemaColumn struct {
+	Column
+	parse func(string) (interface{}, error)
+}
+
+// compileColumns returns the columns of s with their parsers.
+func (s *Schema) compileColumns() ([]schemaColumn, error) {
+	if len(s.Columns) == 0 {
+		return nil, fmt.Errorf("schema has no columns")
+	}
+	columns := make([]schemaColumn, len(s.Columns))
+	for i, column := range s.Columns {
+		parse, err := column.parser()
+		if err != nil {
+			return nil, fmt.Errorf("column %q: %v", column.Name, err)
+		}
+		columns[i] = schemaColumn{Column: column, parse: parse}
+	}
+	return columns, nil
+}
+
+// parser returns a function which converts values of c, which aren't
+// empty, to their type.
+func (c Column) parser() (func(string) (interface{}, error), error) {
+	switch c.Type {
+	case "", TypeString:
+		return func(s string) (interface{}, error) {
+			return s, nil
+		}, nil
+	case TypeInteger:
+		return func(s string) (interface{}, error) {
+			return ParseInt(s)
+		}, nil
+	case TypeNumber:
+		return func(s string) (interface{}, error) {
+			return ParseFloat(s)
+		}, nil
+	case TypeBoolean:
+		return func(s string) (interface{}, error) {
+			return ParseBool(s)
+		}, nil
+	case TypeDate, TypeDateTime:
+		layout := c.Format
+		if layout == "" {
+			layout = DefaultDateFormat
+			if c.Type == TypeDateTime {
+				layout = DefaultDateTimeFormat
+			}
+		}
+		return func(s string) (interface{}, error) {
+			return time.Parse(layout, strings.TrimSpace(s))
+		}, nil
+	case TypeArray:
+		return func(s string) (interface{}, error) {
+			var a []interface{}
+			err := json.Unmarshal([]byte(s), &a)
+			return a, err
+		}, nil
+	case TypeObject:
+		return func(s string) (interface{}, error) {
+			var o map[string]interface{}
+			err := json.Unmarshal([]byte(s), &o)
+			return o, err
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", c.Type)
+}
+
+// matchesKey reports whether a header of key belongs to c.
+func (c *Column) matchesKey(key string) bool {
+	if c.Name == key {
+		return true
+	}
+	for _, alias := range c.Aliases {
+		if alias == key {
+			return true
+		}
+	}
+	return false
+}
+
+// inEnum reports whether value is one of c's Enum.
+func (c *Column) inEnum(value string) bool {
+	for _, v := range c.Enum {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// value applies c's default, requirement and enum to a raw value, and
+// returns it with its typed value, which is nil if it's empty.
+func (c *schemaColumn) value(raw string) (string, interface{}, error) {
+	if strings.TrimSpace(raw) == "" {
+		raw = c.Default
+	}
+	if strings.TrimSpace(raw) == "" {
+		if c.Required {
+			return "", nil, ErrRequired
+		}
+		return raw, nil, nil
+	}
+	if len(c.Enum) > 0 && !c.inEnum(strings.TrimSpace(raw)) {
+		return "", nil, fmt.Errorf("unknown value %q, expected one of %q", strings.TrimSpace(raw), c.Enum)
+	}
+	typed, err := c.parse(raw)
+	if err != nil {
+		return "", nil, err
+	}
+	return raw, typed, nil
+}
+
+// schemaLayout is a Schema matched against a file's headers.
+type schemaLayout struct {
+	columns []schemaColumn
+
+	// names are the columns' names, in schema order.
+	names []string
+
+	// positions are the index in the row of each column, or -1 if
+	// the file doesn't have it.
+	positions []int
+
+	// fileHeaders are the headers of the file, if reading.
+	fileHeaders []string
+}
+
+// validateSchema is validate for Configs with a Schema.  headers is
+// nil when marshalling.
+func (c *Config) validateSchema(headers []string) (*validConfig, error) {
+	outType := reflect.TypeOf(c.Holder)
+	if outType == nil {
+		outType = recordType
+	} else if !isDynamic(outType) {
+		return nil, fmt.Errorf("cannot use %q with a Schema, only Record or map[string]string supported", outType)
+	}
+
+	columns, err := c.Schema.compileColumns()
+	if err != nil {
+		return nil, err
+	}
+	layout := &schemaLayout{
+		columns:   columns,
+		names:     make([]string, len(columns)),
+		positions: make([]int, len(columns)),
+	}
+	for i := range columns {
+		layout.names[i] = columns[i].Name
+		layout.positions[i] = -1
+	}
+
+	if headers != nil {
+		layout.fileHeaders = headers
+		headers = c.headerKeys(headers)
+		if c.FailIfDoubleHeaderNames {
+			if err := maybeDoubleHeaderNames(headers); err != nil {
+				return nil, err
+			}
+		}
+
+		// As with struct tags, headers match exactly before they
+		// match with surrounding whitespace trimmed.
+		for _, trim := range []bool{false, true} {
+			for j, header := range headers {
+				if trim {
+					header = strings.TrimSpace(header)
+				}
+				for i := range columns {
+					if layout.positions[i] < 0 && columns[i].matchesKey(header) {
+						layout.positions[i] = j
+						break
+					}
+				}
+			}
+		}
+
+		matched := false
+		for i, column := range columns {
+			if layout.positions[i] >= 0 {
+				matched = true
+			} else if column.Required && column.Default == "" {
+				return nil, fmt.Errorf("missing required column %q", column.Name)
+			}
+		}
+		if len(headers) > 0 && !matched {
+			return nil, newHeaderError(fmt.Sprintf("expected one or more of headers %v, but got %v ", layout.names, headers),
+				headers, layout.names)
+		}
+	}
+
+	return &validConfig{
+		Config:  *c,
+		outType: outType,
+		headers: layout.names,
+		dynamic: true,
+		schema:  layout,
+	}, nil
+}
+
+// decode returns the values of the schema's columns in row, with
+// their typed values.
+func (l *schemaLayout) decode(row []string, values []string, typed []interface{}) ([]string, []interface{}, error) {
+	for i := range l.columns {
+		raw := ""
+		if j := l.positions[i]; j >= 0 && j < len(row) {
+			raw = row[j]
+		}
+		value, typedValue, err := l.columns[i].value(raw)
+		if err != nil {
+			err = fmt.Errorf("cannot assign column %q at index %v: %w", l.columns[i].Name, l.positions[i], err)
+			return nil, nil, &ColumnError{Index: l.positions[i], Err: err}
+		}
+		values = append(values, value)
+		typed = append(typed, typedValue)
+	}
+	return values, typed, nil
+}
+
+// validate applies the schema's defaults to row, which has a value
+// for each column in schema order, and checks its values.
+func (l *schemaLayout) validate(row []string) error {
+	for i := range l.columns {
+		value, _, err := l.columns[i].value(row[i])
+		if err != nil {
+			return fmt.Errorf("cannot write column %q: %w", l.columns[i].Name, err)
+		}
+		row[i] = value
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package commando
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSchema = &Schema{
+	Columns: []Column{
+		{Name: "sku", Aliases: []string{"item"}, Required: true},
+		{Name: "qty", Type: TypeInteger, Default: "1"},
+		{Name: "price", Type: TypeNumber},
+		{Name: "paid", Type: TypeBoolean},
+		{Name: "date", Type: TypeDate, Format: "02/01/2006"},
+		{Name: "at", Type: TypeDateTime},
+	},
+}
+
+func TestSchema_Unmarshal(t *testing.T) {
+	t.Parallel()
+
+	csvText := `item,extra, qty,price,paid,date,at
+abc,x,3,1.5,yes,02/01/2020,2020-01-02T03:04:05Z
+def,y,,,,,
+,z,2,,,,
+ghi,,two,,,,
+`
+	c := &Config{Schema: testSchema}
+	um, err := c.NewUnmarshaller(csv.NewReader(strings.NewReader(csvText)))
+	require.NoError(t, err)
+
+	var errs []error
+	out, err := um.ReadAll(context.Background(), func(_ context.Context, err error) error {
+		errs = append(errs, err)
+		return nil
+	})
+	require.NoError(t, err)
+	records := out.([]Record)
+	require.Len(t, records, 2)
+
+	rec := records[0]
+	assert.Equal(t, []string{"sku", "qty", "price", "paid", "date", "at"}, rec.Headers)
+	assert.Equal(t, []string{"abc", "3", "1.5", "yes", "02/01/2020", "2020-01-02T03:04:05Z"}, rec.Values)
+	assert.Equal(t, "abc", rec.Value("sku"))
+	assert.Equal(t, int64(3), rec.Value("qty"))
+	assert.Equal(t, 1.5, rec.Value("price"))
+	assert.Equal(t, true, rec.Value("paid"))
+	assert.Equal(t, time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), rec.Value("date"))
+	assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), rec.Value("at"))
+	assert.Nil(t, rec.Value("extra"))
+
+	rec = records[1]
+	assert.Equal(t, int64(1), rec.Value("qty"), "default wasn't applied")
+	assert.Nil(t, rec.Value("price"))
+	qty, err := rec.Int("qty")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), qty)
+
+	require.Len(t, errs, 2)
+	assert.EqualError(t, errs[0], `on line 4, column 1: cannot assign column "sku" at index 0: value is required`)
+	assert.True(t, errors.Is(errs[0], ErrRequired))
+	assert.EqualError(t, errs[1], `on line 5, column 6: cannot assign column "qty" at index 2: strconv.ParseInt: parsing "two": invalid syntax`)
+}
+
+func TestSchema_UnmarshalMap(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{Holder: map[string]string{}, Schema: testSchema}
+	um, err := c.NewUnmarshaller(csv.NewReader(strings.NewReader("sku,paid\nabc,no\n")))
+	require.NoError(t, err)
+
+	out, err := um.Read()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"sku": "abc", "qty": "1", "price": "", "paid": "no", "date": "", "at": ""}, out)
+}
+
+func TestSchema_ValidateHeaders(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{Schema: testSchema}
+	_, err := c.NewUnmarshaller(csv.NewReader(strings.NewReader("qty,price\n")))
+	assert.EqualError(t, err, `missing required column "sku"`)
+
+	_, err = (&Config{Schema: &Schema{Columns: []Column{{Name: "a"}}}}).NewUnmarshaller(csv.NewReader(strings.NewReader("b\n")))
+	assert.EqualError(t, err, "expected one or more of headers [a], but got [b] ")
+
+	_, err = (&Config{Schema: &Schema{Columns: []Column{{Name: "a", Type: "money"}}}}).NewUnmarshaller(csv.NewReader(strings.NewReader("a\n")))
+	assert.EqualError(t, err, `column "a": unknown type "money"`)
+
+	_, err = (&Config{Holder: sample{}, Schema: testSchema}).NewUnmarshaller(csv.NewReader(strings.NewReader("sku\n")))
+	assert.EqualError(t, err, `cannot use "commando.sample" with a Schema, only Record or map[string]string supported`)
+
+	_, err = (&Config{Schema: &Schema{}}).NewUnmarshaller(csv.NewReader(strings.NewReader("sku\n")))
+	assert.EqualError(t, err, "schema has no columns")
+}
+
+func TestSchema_Marshal(t *testing.T) {
+	t.Parallel()
+
+	out := new(bytes.Buffer)
+	c := &Config{Schema: testSchema}
+	m, err := c.NewMarshaller(csv.NewWriter(out))
+	require.NoError(t, err)
+
+	require.NoError(t, m.Write(map[string]string{"sku": "abc", "price": "2.5"}))
+	rec := Record{}
+	rec.Set("sku", "def")
+	rec.Set("date", "03/01/2020")
+	require.NoError(t, m.Write(rec))
+
+	err = m.Write(map[string]string{"price": "2.5"})
+	assert.EqualError(t, err, `cannot write column "sku": value is required`)
+	err = m.Write(map[string]string{"sku": "x", "at": "yesterday"})
+	assert.Error(t, err)
+
+	require.NoError(t, m.Flush())
+	assert.Equal(t, "sku,qty,price,paid,date,at\nabc,1,2.5,,,\ndef,1,,,03/01/2020,\n", out.String())
+}
@@ -0,0 +1,201 @@
+package commando
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MaxSampleValues is the number of bad values Stats keeps for each
+// column.
+const MaxSampleValues = 5
+
+// Stats counts the rows an Unmarshaller has read, and the errors it
+// found in them.  Rows are counted once they're returned by Read or
+// handed to ReadAllCallback's callbacks, so rows which several Workers
+// read ahead of an early stop aren't counted.
+type Stats struct {
+	// Read is the number of rows read, not counting the header.
+	Read int
+
+	// Decoded is the number of rows which were unmarshalled.
+	Decoded int
+
+	// Rejected is the number of rows which couldn't be
+	// unmarshalled.
+	Rejected int
+
+	// Skipped is the number of records the Reader couldn't read,
+	// such as malformed CSV.
+	Skipped int
+
+	// ErrorsByHeader counts rejected rows by the header of the
+	// column which caused the error, for errors about a value.
+	ErrorsByHeader map[string]int
+
+	// ErrorsByKind counts errors by kind: "read" for errors from the
+	// Reader, "columns" for ErrColumnCount, "required" for
+	// ErrRequired, "rule" for ValidationErrors, "conversion" for
+	// other errors about a value, and "row" for anything else, such
+	// as errors from Validate.
+	ErrorsByKind map[string]int
+
+	// BadValues are the first distinct values of each column which
+	// caused errors, up to MaxSampleValues of them.
+	BadValues map[string][]string
+}
+
+// String summarizes s, for people.
+func (s Stats) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "read %d rows: %d decoded, %d rejected, %d skipped", s.Read, s.Decoded, s.Rejected, s.Skipped)
+	if len(s.ErrorsByKind) > 0 {
+		b.WriteString("\nerrors by kind:")
+		for _, kind := range sortedKeys(s.ErrorsByKind) {
+			fmt.Fprintf(&b, " %s %d", kind, s.ErrorsByKind[kind])
+		}
+	}
+	if len(s.ErrorsByHeader) > 0 {
+		b.WriteString("\nerrors by column:")
+		for _, header := range sortedKeys(s.ErrorsByHeader) {
+			fmt.Fprintf(&b, "\n  %q: %d", header, s.ErrorsByHeader[header])
+			if values := s.BadValues[header]; len(values) > 0 {
+				fmt.Fprintf(&b, ", such as %q", values)
+			}
+		}
+	}
+	return b.String()
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Stats returns the Stats of the rows read so far.  It's safe to call
+// while ReadAllCallback is running.
+func (um *Unmarshaller) Stats() Stats {
+	return um.stats.get()
+}
+
+// statsTracker keeps Stats up to date as rows are read, possibly by
+// several goroutines.
+type statsTracker struct {
+	mu    sync.Mutex
+	stats Stats
+}
+
+func (t *statsTracker) get() Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := t.stats
+	s.ErrorsByHeader = copyCounts(s.ErrorsByHeader)
+	s.ErrorsByKind = copyCounts(s.ErrorsByKind)
+	if s.BadValues != nil {
+		s.BadValues = make(map[string][]string, len(t.stats.BadValues))
+		for header, values := range t.stats.BadValues {
+			s.BadValues[header] = append([]string(nil), values...)
+		}
+	}
+	return s
+}
+
+func copyCounts(m map[string]int) map[string]int {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]int, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// addReadError counts err, an error from the Reader.
+func (t *statsTracker) addReadError(err error) {
+	if errors.Is(err, io.EOF) {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stats.Skipped++
+	t.countKind("read")
+}
+
+// addRow counts row, which vc unmarshalled with err.
+func (t *statsTracker) addRow(vc *validConfig, row []string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stats.Read++
+	if err == nil {
+		t.stats.Decoded++
+		return
+	}
+	t.stats.Rejected++
+
+	var ce *ColumnError
+	var ve *ValidationError
+	switch {
+	case errors.Is(err, ErrColumnCount):
+		t.countKind("columns")
+	case errors.Is(err, ErrRequired):
+		t.countKind("required")
+	case errors.As(err, &ve):
+		t.countKind("rule")
+	case errors.As(err, &ce):
+		t.countKind("conversion")
+	default:
+		t.countKind("row")
+	}
+	header, value, ok := vc.errorHeader(row, err)
+	if !ok {
+		return
+	}
+	if t.stats.ErrorsByHeader == nil {
+		t.stats.ErrorsByHeader = make(map[string]int)
+		t.stats.BadValues = make(map[string][]string)
+	}
+	t.stats.ErrorsByHeader[header]++
+	values := t.stats.BadValues[header]
+	if len(values) >= MaxSampleValues {
+		return
+	}
+	for _, v := range values {
+		if v == value {
+			return
+		}
+	}
+	t.stats.BadValues[header] = append(values, value)
+}
+
+// errorHeader returns the header and value of the column of row which
+// err, an error unmarshalling it, is about, if it's about a value.
+func (vc *validConfig) errorHeader(row []string, err error) (string, string, bool) {
+	var ce *ColumnError
+	if !errors.As(err, &ce) || ce.Index < 0 || ce.Index >= len(row) {
+		return "", "", false
+	}
+	// Variants each have their own headers.
+	if variant, err := vc.variantFor(row); err == nil {
+		vc = variant
+	}
+	headers := vc.fileHeaders()
+	if ce.Index >= len(headers) {
+		return "", "", false
+	}
+	return headers[ce.Index], row[ce.Index], true
+}
+
+func (t *statsTracker) countKind(kind string) {
+	if t.stats.ErrorsByKind == nil {
+		t.stats.ErrorsByKind = make(map[string]int)
+	}
+	t.stats.ErrorsByKind[kind]++
+}
@@ -0,0 +1,77 @@
+package commando
+
+import (
+	"context"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type statsStruct struct {
+	Name  string `csv:"name,required"`
+	Age   int    `csv:"age,max=150"`
+	State string `csv:"state,oneof=NY|CA"`
+}
+
+func TestUnmarshaller_Stats(t *testing.T) {
+	t.Parallel()
+
+	data := "name,age,state\n" +
+		"ann,1,NY\n" +
+		"bob,x,NY\n" +
+		"cy,y,CA\n" +
+		"dee,x,CA\n" +
+		",2,CA\n" +
+		"eve,200,CA\n" +
+		"fay,3,TX\n" +
+		"gus,4\n" +
+		"\"bad\n"
+
+	for _, workers := range []int{0, 3} {
+		reader := csv.NewReader(strings.NewReader(data))
+		reader.FieldsPerRecord = -1
+		c := &Config{Holder: statsStruct{}, Workers: workers, RaggedRows: RaggedError, EnforceRequired: true}
+		um, err := c.NewUnmarshaller(reader)
+		require.NoError(t, err)
+		assert.Equal(t, Stats{}, um.Stats())
+
+		_, err = um.ReadAll(context.Background(), func(context.Context, error) error {
+			return nil
+		})
+		require.NoError(t, err)
+
+		stats := um.Stats()
+		assert.Equal(t, 8, stats.Read)
+		assert.Equal(t, 1, stats.Decoded)
+		assert.Equal(t, 7, stats.Rejected)
+		assert.Equal(t, 1, stats.Skipped)
+		assert.Equal(t, map[string]int{"name": 1, "age": 4, "state": 1}, stats.ErrorsByHeader)
+		assert.Equal(t, map[string]int{
+			"read":       1,
+			"columns":    1,
+			"required":   1,
+			"rule":       2,
+			"conversion": 3,
+		}, stats.ErrorsByKind)
+		// Workers decode rows in no particular order.
+		assert.ElementsMatch(t, []string{"x", "y", "200"}, stats.BadValues["age"])
+		assert.Equal(t, []string{""}, stats.BadValues["name"])
+		assert.Equal(t, []string{"TX"}, stats.BadValues["state"])
+
+		if workers == 0 {
+			assert.Equal(t, `read 8 rows: 1 decoded, 7 rejected, 1 skipped
+errors by kind: columns 1 conversion 3 read 1 required 1 rule 2
+errors by column:
+  "age": 4, such as ["x" "y" "200"]
+  "name": 1, such as [""]
+  "state": 1, such as ["TX"]`, stats.String())
+		}
+
+		// Stats are copies.
+		stats.BadValues["name"][0] = "changed"
+		assert.Equal(t, "", um.Stats().BadValues["name"][0])
+	}
+}
@@ -0,0 +1,115 @@
+package commando
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// HeaderError is the error for headers which don't match the keys of
+// the Holder's fields, or of the Schema's columns.
+type HeaderError struct {
+	msg string
+
+	// Suggestions pair headers with the keys they're probably
+	// misspellings of.
+	Suggestions []Suggestion
+}
+
+// Suggestion pairs a header with the key it's probably a misspelling
+// of.
+type Suggestion struct {
+	Header string
+	Key    string
+}
+
+func (e *HeaderError) Error() string {
+	if len(e.Suggestions) == 0 {
+		return e.msg
+	}
+	var b strings.Builder
+	b.WriteString(e.msg)
+	b.WriteString("; did you mean ")
+	for i, s := range e.Suggestions {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%q for %q", s.Key, s.Header)
+	}
+	b.WriteString("?")
+	return b.String()
+}
+
+// newHeaderError returns a HeaderError with msg, suggesting one of
+// keys for each of headers which resembles one.
+func newHeaderError(msg string, headers, keys []string) *HeaderError {
+	e := &HeaderError{msg: msg}
+	for _, header := range headers {
+		if key, ok := closestKey(header, keys); ok {
+			e.Suggestions = append(e.Suggestions, Suggestion{Header: header, Key: key})
+		}
+	}
+	return e
+}
+
+// closestKey returns the key which header is closest to, if it's
+// close enough to be a misspelling: within one edit for every three
+// characters of the key.  Headers and keys are compared
+// ignoring case and anything but letters and digits, so "Client ID"
+// is as close as can be to "client_id".
+func closestKey(header string, keys []string) (string, bool) {
+	normalized := normalizeHeader(header)
+	best, bestDistance := "", -1
+	for _, key := range keys {
+		if key == header {
+			continue
+		}
+		k := normalizeHeader(key)
+		d := editDistance(normalized, k)
+		if d <= len([]rune(k))/3 && (bestDistance < 0 || d < bestDistance) {
+			best, bestDistance = key, d
+		}
+	}
+	return best, bestDistance >= 0
+}
+
+func normalizeHeader(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	curr := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		curr[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			curr[j] = min3(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(br)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
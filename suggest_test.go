@@ -0,0 +1,60 @@
+package commando
+
+import (
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type suggestedStruct struct {
+	ClientID int    `csv:"client_id"`
+	Name     string `csv:"name"`
+	Email    string `csv:"email"`
+}
+
+func TestHeaderError_Suggestions(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewUnmarshaller(suggestedStruct{}, csv.NewReader(strings.NewReader("clent_id,Full Name,E-Mail\n")))
+	assert.EqualError(t, err, `expected one or more of headers [client_id name email], but got [clent_id Full Name E-Mail] ; did you mean "client_id" for "clent_id", "email" for "E-Mail"?`)
+
+	var he *HeaderError
+	require.True(t, errors.As(err, &he))
+	assert.Equal(t, []Suggestion{
+		{Header: "clent_id", Key: "client_id"},
+		{Header: "E-Mail", Key: "email"},
+	}, he.Suggestions)
+}
+
+func TestHeaderError_FailIfUnmatchedStructTags(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{Holder: suggestedStruct{}, FailIfUnmatchedStructTags: true}
+	_, err := c.NewUnmarshaller(csv.NewReader(strings.NewReader("name,Client ID,mail\n")))
+	assert.EqualError(t, err, `found unmatched struct field with tags [client_id email]; did you mean "client_id" for "Client ID", "email" for "mail"?`)
+
+	_, err = c.NewUnmarshaller(csv.NewReader(strings.NewReader("name,client_id,other\n")))
+	assert.EqualError(t, err, `found unmatched struct field with tags [email]`)
+}
+
+func TestEditDistance(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		a, b string
+		d    int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"clentid", "clientid", 1},
+		{"héllo", "hello", 1},
+	} {
+		assert.Equal(t, test.d, editDistance(test.a, test.b), "%s %s", test.a, test.b)
+		assert.Equal(t, test.d, editDistance(test.b, test.a), "%s %s", test.b, test.a)
+	}
+}
@@ -1,6 +1,7 @@
 package commando
 
 import (
+	"context"
 	"encoding"
 	"fmt"
 	"reflect"
@@ -30,6 +31,41 @@ type TypeUnmarshalCSVWithFields interface {
 	UnmarshalCSVWithFields(key, value string) error
 }
 
+// RecordUnmarshaller can be implemented on whole structs to decode
+// a row without reflection.  commando-gen generates implementations
+// which follow the same rules as the reflection-based decoder.
+type RecordUnmarshaller interface {
+	UnmarshalCSVRecord(headers, row []string) error
+}
+
+// RecordMarshaller can be implemented on whole structs to encode a
+// row, in struct field order, without reflection.
+type RecordMarshaller interface {
+	MarshalCSVRecord() ([]string, error)
+}
+
+// AfterUnmarshaller can be implemented on whole structs to be called
+// once all of a record's fields have been unmarshalled, for example
+// to set fields derived from others.  ctx is the context passed to
+// ReadAllCallback or Stream.
+type AfterUnmarshaller interface {
+	AfterUnmarshalCSV(ctx context.Context) error
+}
+
+// Validator can be implemented on whole structs to check a record
+// once it has been unmarshalled, and after AfterUnmarshalCSV, for
+// example to compare fields with each other.
+type Validator interface {
+	Validate() error
+}
+
+// BeforeMarshaller can be implemented on whole structs to be called
+// before a record is marshalled.  If the record was passed by value,
+// changes are made to a copy.
+type BeforeMarshaller interface {
+	BeforeMarshalCSV() error
+}
+
 // NoUnmarshalFuncError is the custom error type to be raised in case there is no unmarshal function defined on type
 type NoUnmarshalFuncError struct {
 	msg string
@@ -75,20 +111,69 @@ func toString(in interface{}) (string, error) {
 	return "", fmt.Errorf("No known conversion from %T to string", inValue)
 }
 
+// The Parse functions convert CSV values the same way fields are
+// decoded.  They're exported for code generated by commando-gen.
+
+// ParseBool converts a CSV value to a bool.  In addition to the
+// syntax accepted by strconv.ParseBool, "yes" and "no" are accepted,
+// and an empty value is false.
+func ParseBool(s string) (bool, error) {
+	s = strings.TrimSpace(s)
+	if strings.EqualFold(s, "yes") {
+		return true, nil
+	} else if strings.EqualFold(s, "no") || s == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(s)
+}
+
+// ParseInt converts a CSV value to an int64.  Any fractional part is
+// discarded, and an empty value is 0.
+func ParseInt(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		s = s[:i]
+	}
+	return strconv.ParseInt(s, 0, 64)
+}
+
+// ParseUint converts a CSV value to a uint64.  Any fractional part is
+// discarded, and an empty value is 0.
+func ParseUint(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	// support the float input
+	if strings.Contains(s, ".") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(f), nil
+	}
+	return strconv.ParseUint(s, 0, 64)
+}
+
+// ParseFloat converts a CSV value to a float64.  An empty value is 0.
+func ParseFloat(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
 func toBool(in interface{}) (bool, error) {
 	inValue := reflect.ValueOf(in)
 
 	switch inValue.Kind() {
 	case reflect.String:
-		s := inValue.String()
-		s = strings.TrimSpace(s)
-		if strings.EqualFold(s, "yes") {
-			return true, nil
-		} else if strings.EqualFold(s, "no") || s == "" {
-			return false, nil
-		} else {
-			return strconv.ParseBool(s)
-		}
+		return ParseBool(inValue.String())
 	case reflect.Bool:
 		return inValue.Bool(), nil
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
@@ -118,12 +203,7 @@ func toInt(in interface{}) (int64, error) {
 
 	switch inValue.Kind() {
 	case reflect.String:
-		s := strings.TrimSpace(inValue.String())
-		if s == "" {
-			return 0, nil
-		}
-		out := strings.SplitN(s, ".", 2)
-		return strconv.ParseInt(out[0], 0, 64)
+		return ParseInt(inValue.String())
 	case reflect.Bool:
 		if inValue.Bool() {
 			return 1, nil
@@ -144,20 +224,7 @@ func toUint(in interface{}) (uint64, error) {
 
 	switch inValue.Kind() {
 	case reflect.String:
-		s := strings.TrimSpace(inValue.String())
-		if s == "" {
-			return 0, nil
-		}
-
-		// support the float input
-		if strings.Contains(s, ".") {
-			f, err := strconv.ParseFloat(s, 64)
-			if err != nil {
-				return 0, err
-			}
-			return uint64(f), nil
-		}
-		return strconv.ParseUint(s, 0, 64)
+		return ParseUint(inValue.String())
 	case reflect.Bool:
 		if inValue.Bool() {
 			return 1, nil
@@ -178,11 +245,7 @@ func toFloat(in interface{}) (float64, error) {
 
 	switch inValue.Kind() {
 	case reflect.String:
-		s := strings.TrimSpace(inValue.String())
-		if s == "" {
-			return 0, nil
-		}
-		return strconv.ParseFloat(s, 64)
+		return ParseFloat(inValue.String())
 	case reflect.Bool:
 		if inValue.Bool() {
 			return 1, nil
@@ -2,10 +2,13 @@ package commando
 
 import (
 	"context"
+	"encoding/csv"
 	"errors"
 	"fmt"
 	"io"
 	"reflect"
+	"strings"
+	"time"
 )
 
 // Unmarshaller is a CSV to struct unmarshaller.
@@ -13,6 +16,22 @@ type Unmarshaller struct {
 	config *validConfig
 	line   int
 	reader Reader
+
+	// index is the number of rows read, not counting the header, and
+	// last describes the most recent one.
+	index int
+	last  RowMeta
+
+	// endLine is the line the most recent row, or the header, ends
+	// on.
+	endLine int
+
+	// delivered is where the last row ReadAllCallback handed to its
+	// callbacks ends, which Checkpoint uses in case its Workers read
+	// further ahead.  Reading another row clears it.
+	delivered *Checkpoint
+
+	stats statsTracker
 }
 
 // NewUnmarshaller is a convenience function which allocates and
@@ -29,36 +48,151 @@ func NewUnmarshallerWithID(holder interface{}, reader Reader, idName string) (*U
 
 // NewUnmarshaller creates an unmarshaller from a Reader and a struct.
 func (c *Config) NewUnmarshaller(reader Reader) (*Unmarshaller, error) {
+	if len(c.Variants) > 0 {
+		vc, err := c.validate(nil)
+		if err != nil {
+			return nil, err
+		}
+		return &Unmarshaller{reader: reader, config: vc}, nil
+	}
+	if hr, ok := reader.(headerless); ok {
+		vc, err := c.validate(hr.headers())
+		if err != nil {
+			return nil, err
+		}
+		vc.warnHeaders()
+		if c.Observer != nil {
+			c.Observer.OnHeader(vc.fileHeaders())
+		}
+		return &Unmarshaller{reader: reader, config: vc}, nil
+	}
+
 	headers, err := reader.Read()
 	if err != nil {
 		return nil, err
 	}
+	// Copy the headers, in case the Reader reuses its records.
+	headers = append([]string(nil), headers...)
 
 	vc, err := c.validate(headers)
 	if err != nil {
 		return nil, err
 	}
+	vc.warnHeaders()
+	if c.Observer != nil {
+		c.Observer.OnHeader(headers)
+	}
 
 	um := &Unmarshaller{
 		reader: reader,
 		config: vc,
 		// Start at L1 because reader.Read() was called for headers.
-		line: 1,
+		line:    1,
+		endLine: 1,
+	}
+	if pr, ok := reader.(PositionedReader); ok && len(headers) > 0 {
+		um.endLine = endLine(pr, headers)
 	}
 
 	return um, nil
 }
 
 // Read returns an interface{} whose runtime type is the same as the
-// struct that was used to create the Unmarshaller.
+// struct, Record or map that was used to create the Unmarshaller.
+//
+// If the struct implements AfterUnmarshaller or Validator, their
+// methods are called once its fields are set, with a background
+// context.
 func (um *Unmarshaller) Read() (interface{}, error) {
-	row, err := um.reader.Read()
+	return um.read(context.Background())
+}
+
+// read is Read, passing ctx to the holder's hooks.
+func (um *Unmarshaller) read(ctx context.Context) (interface{}, error) {
+	row, err := um.readRow()
 	if err != nil {
+		um.stats.addReadError(err)
 		return nil, err
 	}
-	um.line++
-	out, err := um.unmarshalRow(row)
-	return out, wrapLine(err, um.line)
+	rec, err := um.decodeRow(ctx, row, um.last.Index, um.last.StartLine, nil)
+	um.stats.addRow(um.config, row, err)
+	return rec, err
+}
+
+// ReadInto reads the next record into dst, which must be a pointer
+// to the struct, Record or map that was used to create the
+// Unmarshaller.  dst is
+// reset to its zero value first, so fields which aren't present in
+// the row don't keep values from a previous call.
+//
+// Unlike Read, ReadInto doesn't allocate a new struct for every
+// record, so a loop calling it with the same dst doesn't allocate
+// beyond what the Reader and any pointer fields require.  It is safe
+// to use with a csv.Reader which has ReuseRecord set.
+func (um *Unmarshaller) ReadInto(dst interface{}) error {
+	if um.config.variants != nil {
+		return errors.New("ReadInto can't be used with Variants, use Read")
+	}
+
+	dstValue := reflect.ValueOf(dst)
+	structType := um.config.outType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if dstValue.Kind() != reflect.Ptr || dstValue.Type().Elem() != structType || dstValue.IsNil() {
+		return fmt.Errorf("Expected non-nil *%s, but got %T", structType, dst)
+	}
+
+	row, err := um.readRow()
+	if err != nil {
+		um.stats.addReadError(err)
+		return err
+	}
+	var start time.Time
+	if um.config.Observer != nil {
+		start = time.Now()
+	}
+	err = um.readInto(dstValue.Elem(), structType, row)
+	um.stats.addRow(um.config, row, err)
+	err = um.positionError(err, row, um.last.Index, um.last.StartLine, nil)
+	if um.config.Observer != nil {
+		um.observeRow(start, um.last.StartLine, row, err)
+	}
+	return err
+}
+
+// readInto sets dst, an addressable holder of type structType, from
+// row.
+func (um *Unmarshaller) readInto(dst reflect.Value, structType reflect.Type, row []string) error {
+	if err := um.config.checkColumnCount(row, um.last.StartLine); err != nil {
+		return err
+	}
+	if um.config.dynamic {
+		return um.config.decodeDynamic(dst, row)
+	}
+
+	dst.Set(reflect.Zero(structType))
+	if err := um.config.unmarshalRowInto(dst, row); err != nil {
+		return err
+	}
+	return um.config.afterUnmarshal(context.Background(), dst)
+}
+
+// decodeRow converts row, the record at index, which was read from
+// line, to a struct.  fields are the positions of its fields, or nil
+// if the Reader is still on the row.  The caller counts the row in the
+// Stats, once it's delivered.
+func (um *Unmarshaller) decodeRow(ctx context.Context, row []string, index, line int, fields []fieldPosition) (interface{}, error) {
+	var start time.Time
+	if um.config.Observer != nil {
+		start = time.Now()
+	}
+	out, err := um.unmarshalRow(ctx, row, line)
+	err = um.positionError(err, row, index, line, fields)
+	if um.config.Observer != nil {
+		um.observeRow(start, line, row, err)
+	}
+	return out, err
 }
 
 // ReadAll returns a slice of structs.
@@ -79,14 +213,48 @@ func (um *Unmarshaller) ReadAll(ctx context.Context, onError func(ctx context.Co
 // processing continues; if it returns an error, processing stops and
 // its error (not the one returned by Read()) is returned.
 //
+// If onError is nil, the Config's OnError or ErrorHandler is used
+// instead, and if both are nil, processing stops on the first error.
+//
 // If onSuccess() returns an error, processing stops and its error is
 // returned.
+//
+// If ctx is cancelled, processing stops and ctx.Err() is returned.
+//
+// If the Config has more than one Worker, rows are decoded in
+// parallel, but onSuccess() and onError() are still called from the
+// calling goroutine, in the order the rows were read unless
+// Config.Unordered is set.
 func (um *Unmarshaller) ReadAllCallback(ctx context.Context,
 	onSuccess func(context.Context, interface{}) error,
 	onError func(context.Context, error) error,
 ) error {
+	if onError == nil {
+		onError = StopOnError
+		if um.config.OnError != nil {
+			onError = um.config.OnError
+		} else if handler := um.config.ErrorHandler; handler != nil {
+			onError = func(_ context.Context, err error) error {
+				return handler(err)
+			}
+		}
+	}
+	// Give error budgets, such as MaxErrors, a fresh start.
+	errCtx := withErrorScope(ctx)
+	handleError := onError
+	onError = func(_ context.Context, err error) error {
+		return handleError(errCtx, err)
+	}
+	if um.config.Workers > 1 {
+		return um.readAllParallel(ctx, onSuccess, onError)
+	}
+
 	for {
-		rec, err := um.Read()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rec, err := um.read(ctx)
 		if errors.Is(err, io.EOF) {
 			return nil
 		} else if err != nil {
@@ -101,23 +269,65 @@ func (um *Unmarshaller) ReadAllCallback(ctx context.Context,
 			return err
 		}
 	}
-	return nil
 }
 
-// wrapLine wraps err, including the line the error occurred on.
-func wrapLine(err error, line int) error {
-	if err != nil {
-		return fmt.Errorf("on line %d: %w", line, err)
-	}
-	return err
+// Result is a record or error produced by Stream.
+type Result struct {
+	// Record is the record which was read, if Err is nil.
+	Record interface{}
+
+	// Err is the error returned by Read(), if any.
+	Err error
+}
+
+// Stream reads records from um in a new goroutine, sending each
+// record or error on the returned channel.  Errors about a row, such
+// as a *PositionError or *csv.ParseError, don't stop the stream; any
+// other error, such as the Reader failing, is sent and ends it.  The
+// channel is closed once the input is exhausted, the stream ends or
+// ctx is cancelled.
+//
+// Callers must either drain the channel or cancel ctx, otherwise the
+// goroutine leaks.  um must not be used by anything else until the
+// channel is closed.
+func (um *Unmarshaller) Stream(ctx context.Context) <-chan Result {
+	results := make(chan Result)
+	go func() {
+		defer close(results)
+		for ctx.Err() == nil {
+			rec, err := um.read(ctx)
+			if errors.Is(err, io.EOF) {
+				return
+			}
+
+			select {
+			case results <- Result{Record: rec, Err: err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil && !isRowError(err) {
+				return
+			}
+		}
+	}()
+	return results
+}
+
+// isRowError reports whether err is about a single row, so that
+// reading can carry on after it, rather than a failure of the Reader
+// which is likely to recur.
+func isRowError(err error) bool {
+	var pe *PositionError
+	var cpe *csv.ParseError
+	return errors.As(err, &pe) || errors.As(err, &cpe)
 }
 
 // createNew allocates and returns a new holder to unmarshal data
 // into.
-func (um *Unmarshaller) createNew() (reflect.Value, bool) {
+func (vc *validConfig) createNew() (reflect.Value, bool) {
 	isPointer := false
-	concreteOutType := um.config.outType
-	if um.config.outType.Kind() == reflect.Ptr {
+	concreteOutType := vc.outType
+	if vc.outType.Kind() == reflect.Ptr {
 		isPointer = true
 		concreteOutType = concreteOutType.Elem()
 	}
@@ -125,27 +335,84 @@ func (um *Unmarshaller) createNew() (reflect.Value, bool) {
 	return outValue, isPointer
 }
 
-// unmarshalRow converts a CSV row to a struct, based on CSV struct
-// tags.
-func (um *Unmarshaller) unmarshalRow(row []string) (interface{}, error) {
-	outValue, isPointer := um.createNew()
-	idColumn := um.config.idName
+// unmarshalRow converts a CSV row, read from line, to a struct,
+// based on CSV struct tags.
+func (um *Unmarshaller) unmarshalRow(ctx context.Context, row []string, line int) (interface{}, error) {
+	vc, err := um.config.variantFor(row)
+	if err != nil {
+		return nil, err
+	}
+	if err := vc.checkColumnCount(row, line); err != nil {
+		return nil, err
+	}
+	outValue, isPointer := vc.createNew()
+	outStruct := outValue
+	if isPointer {
+		outStruct = outValue.Elem()
+	}
+	if err := vc.unmarshalRowInto(outStruct, row); err != nil {
+		return nil, err
+	}
+	if err := vc.afterUnmarshal(ctx, outStruct); err != nil {
+		return nil, err
+	}
+	return outValue.Interface(), nil
+}
+
+// afterUnmarshal calls the AfterUnmarshaller and Validator methods of
+// outStruct, an addressable struct whose fields have been set, if it
+// has them.
+func (vc *validConfig) afterUnmarshal(ctx context.Context, outStruct reflect.Value) error {
+	if vc.afterUnmarshaller {
+		if err := outStruct.Addr().Interface().(AfterUnmarshaller).AfterUnmarshalCSV(ctx); err != nil {
+			return err
+		}
+	}
+	if vc.validator {
+		return outStruct.Addr().Interface().(Validator).Validate()
+	}
+	return nil
+}
+
+// unmarshalRowInto sets the fields of outStruct, an addressable
+// struct, from a CSV row.
+func (vc *validConfig) unmarshalRowInto(outStruct reflect.Value, row []string) error {
+	if vc.dynamic {
+		return vc.decodeDynamic(outStruct, row)
+	}
+	if vc.recordUnmarshaller {
+		if err := outStruct.Addr().Interface().(RecordUnmarshaller).UnmarshalCSVRecord(vc.headers, row); err != nil {
+			return err
+		}
+		return vc.checkRules(row)
+	}
+
+	idColumn := vc.idName
 	id := ""
 
 	for j, csvColumnContent := range row {
-		if j < len(um.config.fieldInfoMap) && um.config.fieldInfoMap[j] != nil {
-			if id == "" && idColumn != "" && um.config.headers[j] == idColumn {
+		if j < len(vc.fieldInfoMap) && vc.fieldInfoMap[j] != nil {
+			if id == "" && idColumn != "" && vc.headers[j] == idColumn {
 				id = csvColumnContent
 			}
 
-			fieldInfo := um.config.fieldInfoMap[j]
-			if err := setInnerField(&outValue, isPointer, fieldInfo.IndexChain, csvColumnContent, fieldInfo.omitEmpty); err != nil { // Set field of struct
+			fieldInfo := vc.fieldInfoMap[j]
+			field := fieldForWrite(outStruct, fieldInfo.IndexChain)
+			var err error
+			if vc.EnforceRequired && fieldInfo.required && strings.TrimSpace(csvColumnContent) == "" {
+				err = ErrRequired
+			} else {
+				err = fieldInfo.decode(field, csvColumnContent) // Set field of struct
+			}
+			if err != nil {
 				if id != "" {
-					return nil, fmt.Errorf("ID %s - cannot assign field %q at index %v through index chain %v with ID : %v", id, um.config.headers[j], j, fieldInfo.IndexChain, err)
+					err = fmt.Errorf("ID %s - cannot assign field %q at index %v through index chain %v with ID : %w", id, vc.headers[j], j, fieldInfo.IndexChain, err)
+				} else {
+					err = fmt.Errorf("cannot assign field %q at index %v through index chain %v: %w", vc.headers[j], j, fieldInfo.IndexChain, err)
 				}
-				return nil, fmt.Errorf("cannot assign field %q at index %v through index chain %v: %v", um.config.headers[j], j, fieldInfo.IndexChain, err)
+				return &ColumnError{Index: j, Err: err}
 			}
 		}
 	}
-	return outValue.Interface(), nil
+	return vc.checkRules(row)
 }
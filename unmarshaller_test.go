@@ -3,10 +3,12 @@ package commando
 import (
 	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -268,3 +270,213 @@ func Test_Unmarshaller_Allocation(t *testing.T) {
 	require.Error(t, err, "Expected error")
 	require.Nil(t, um, "Expected no Unmarshaller")
 }
+
+func Test_ReadAll_Cancelled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	um, err := NewUnmarshaller(sample{}, csv.NewReader(strings.NewReader(csvContents)))
+	require.NoError(t, err)
+
+	var records []interface{}
+	err = um.ReadAllCallback(ctx, func(_ context.Context, rec interface{}) error {
+		records = append(records, rec)
+		cancel()
+		return nil
+	}, StopOnError)
+	require.Equal(t, context.Canceled, err)
+	assert.Len(t, records, 1, "Expected processing to stop after cancellation")
+
+	um, err = NewUnmarshaller(sample{}, csv.NewReader(strings.NewReader(csvContents)))
+	require.NoError(t, err)
+
+	_, err = um.ReadAll(ctx, StopOnError)
+	require.Equal(t, context.Canceled, err)
+}
+
+func Test_Stream(t *testing.T) {
+	t.Parallel()
+
+	um, err := NewUnmarshaller(sample{}, csv.NewReader(strings.NewReader(brokenCSV)))
+	require.NoError(t, err)
+
+	var records []interface{}
+	var errs []error
+	for res := range um.Stream(context.Background()) {
+		if res.Err != nil {
+			errs = append(errs, res.Err)
+			continue
+		}
+		records = append(records, res.Record)
+	}
+	assert.Equal(t, []interface{}{sample{"a", "b"}, sample{"c", "d"}, sample{"k", "l"}}, records)
+	require.Len(t, errs, 3)
+	assert.Contains(t, errs[0].Error(), "line 4")
+}
+
+func Test_Stream_ReaderError(t *testing.T) {
+	t.Parallel()
+
+	// A csv.Reader returns the same error on every call once its
+	// input fails, so the stream ends.
+	broken := errors.New("broken")
+	um, err := NewUnmarshaller(sample{}, csv.NewReader(io.MultiReader(strings.NewReader("field_a,field_b\na,b\n"), iotest.ErrReader(broken))))
+	require.NoError(t, err)
+
+	var results []Result
+	for res := range um.Stream(context.Background()) {
+		results = append(results, res)
+	}
+	assert.Equal(t, []Result{{Record: sample{"a", "b"}}, {Err: broken}}, results)
+}
+
+func Test_Stream_Cancelled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	um, err := NewUnmarshaller(sample{}, csv.NewReader(strings.NewReader(csvContents)))
+	require.NoError(t, err)
+
+	results := um.Stream(ctx)
+	res := <-results
+	require.NoError(t, res.Err)
+	assert.Equal(t, sample{"a", "b"}, res.Record)
+
+	cancel()
+	for range results {
+		// The stream may deliver at most the record it was sending
+		// when ctx was cancelled.
+	}
+}
+
+func Test_ReadInto(t *testing.T) {
+	t.Parallel()
+
+	csvText := `field_a,field_b
+a,b
+c
+e,f
+`
+	reader := csv.NewReader(strings.NewReader(csvText))
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	um, err := NewUnmarshaller(&sample{}, reader)
+	require.NoError(t, err)
+
+	var rec sample
+	require.NoError(t, um.ReadInto(&rec))
+	assert.Equal(t, sample{"a", "b"}, rec)
+
+	// field_b is missing, so it's reset rather than keeping "b"
+	require.NoError(t, um.ReadInto(&rec))
+	assert.Equal(t, sample{"c", ""}, rec)
+
+	require.NoError(t, um.ReadInto(&rec))
+	assert.Equal(t, sample{"e", "f"}, rec)
+
+	require.Equal(t, io.EOF, um.ReadInto(&rec))
+
+	assert.Error(t, um.ReadInto(rec), "Expected an error for a non-pointer")
+	assert.Error(t, um.ReadInto(&otherSample{}), "Expected an error for the wrong type")
+	assert.Error(t, um.ReadInto((*sample)(nil)), "Expected an error for a nil pointer")
+}
+
+type otherSample struct {
+	A int `csv:"field_a"`
+}
+
+// repeatRowReader returns the same row, count times.
+type repeatRowReader struct {
+	row   []string
+	count int
+}
+
+func (r *repeatRowReader) Read() ([]string, error) {
+	if r.count == 0 {
+		return nil, io.EOF
+	}
+	r.count--
+	return r.row, nil
+}
+
+func Test_ReadInto_Allocations(t *testing.T) {
+	type row struct {
+		Name  string  `csv:"name"`
+		Qty   int     `csv:"qty"`
+		Price float64 `csv:"price"`
+		Paid  bool    `csv:"paid"`
+	}
+
+	reader := &repeatRowReader{row: []string{"name", "qty", "price", "paid"}, count: 1000}
+	um, err := NewUnmarshaller(row{}, reader)
+	require.NoError(t, err)
+	reader.row = []string{"widget", "3", "9.5", "yes"}
+
+	var rec row
+	allocs := testing.AllocsPerRun(100, func() {
+		if err := um.ReadInto(&rec); err != nil {
+			t.Fatal(err)
+		}
+	})
+	assert.Equal(t, row{"widget", 3, 9.5, true}, rec)
+	assert.Zero(t, allocs, "Expected ReadInto not to allocate")
+}
+
+type hookKey struct{}
+
+type period struct {
+	Start  int    `csv:"start"`
+	End    int    `csv:"end"`
+	Length int    `csv:"-"`
+	Source string `csv:"-"`
+}
+
+func (p *period) AfterUnmarshalCSV(ctx context.Context) error {
+	p.Length = p.End - p.Start
+	if source, ok := ctx.Value(hookKey{}).(string); ok {
+		p.Source = source
+	}
+	return nil
+}
+
+func (p *period) Validate() error {
+	if p.Length < 0 {
+		return fmt.Errorf("end %d is before start %d", p.End, p.Start)
+	}
+	return nil
+}
+
+func (p period) BeforeMarshalCSV() error {
+	if p.End == 0 {
+		return fmt.Errorf("period has no end")
+	}
+	return nil
+}
+
+func Test_Hooks(t *testing.T) {
+	t.Parallel()
+
+	csvText := "start,end\n1,3\n5,2\n"
+	um, err := NewUnmarshaller(period{}, csv.NewReader(strings.NewReader(csvText)))
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), hookKey{}, "test")
+	var errs []string
+	out, err := um.ReadAll(ctx, func(_ context.Context, err error) error {
+		errs = append(errs, err.Error())
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []period{{Start: 1, End: 3, Length: 2, Source: "test"}}, out)
+	assert.Equal(t, []string{"on line 3: end 2 is before start 5"}, errs)
+
+	um, err = NewUnmarshaller(&period{}, csv.NewReader(strings.NewReader(csvText)))
+	require.NoError(t, err)
+	var p period
+	require.NoError(t, um.ReadInto(&p))
+	assert.Equal(t, 2, p.Length)
+	assert.EqualError(t, um.ReadInto(&p), "on line 3: end 2 is before start 5")
+}
@@ -1,9 +1,169 @@
 package commando
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
 
 // StopOnError is a helper which stops processing a file on the first
 // encountered error.
 func StopOnError(_ context.Context, err error) error {
 	return err
 }
+
+// ErrorFunc decides whether to carry on processing a file after an
+// error, like the onError func of ReadAllCallback.  If it returns nil,
+// processing continues.
+type ErrorFunc func(ctx context.Context, err error) error
+
+// ChainErrors returns an ErrorFunc which passes each error to funcs in
+// turn, stopping at the first which returns an error.
+func ChainErrors(funcs ...ErrorFunc) ErrorFunc {
+	return func(ctx context.Context, err error) error {
+		for _, f := range funcs {
+			if err := f(ctx, err); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// CollectErrors returns an ErrorFunc which appends each error to
+// errs, and carries on.  It may be used by several imports at once.
+func CollectErrors(errs *[]error) ErrorFunc {
+	var mu sync.Mutex
+	return func(_ context.Context, err error) error {
+		mu.Lock()
+		defer mu.Unlock()
+		*errs = append(*errs, err)
+		return nil
+	}
+}
+
+// BudgetError is the error for an error which exceeds the budget of
+// MaxErrors or MaxErrorRate.
+type BudgetError struct {
+	// Errors is the number of errors, including Err.
+	Errors int
+
+	// Rows is the number of rows read, or 0 if it's unknown.
+	Rows int
+
+	// Limit describes the budget, such as "50 errors".
+	Limit string
+
+	// Err is the error which exceeded the budget.
+	Err error
+}
+
+func (e *BudgetError) Error() string {
+	if e.Rows > 0 {
+		return fmt.Sprintf("%d errors in %d rows, more than the %s tolerated: %v", e.Errors, e.Rows, e.Limit, e.Err)
+	}
+	return fmt.Sprintf("%d errors, more than the %s tolerated: %v", e.Errors, e.Limit, e.Err)
+}
+
+func (e *BudgetError) Unwrap() error {
+	return e.Err
+}
+
+// MaxErrors returns an ErrorFunc which tolerates n errors, and stops
+// processing with a BudgetError on the next.  Each call of
+// ReadAllCallback has its own budget, even if the ErrorFunc is shared,
+// such as through Config.OnError.
+func MaxErrors(n int) ErrorFunc {
+	var shared errorBudget
+	return func(ctx context.Context, err error) error {
+		budget := lockBudget(ctx, &shared)
+		defer budget.mu.Unlock()
+		budget.add(err)
+		if budget.errors > n {
+			return budget.exceeded(fmt.Sprintf("%d errors", n), err)
+		}
+		return nil
+	}
+}
+
+// MaxErrorRate returns an ErrorFunc which stops processing with a
+// BudgetError when more than pct percent of rows have failed, once
+// at least minRows rows have been read.  Like MaxErrors, each call of
+// ReadAllCallback has its own budget.
+//
+// Rows are counted from the errors' indexes, so a *csv.ParseError,
+// which has no index, can't be put in proportion; it's left out of
+// both counts, and doesn't stop processing.  Other errors without a
+// position, such as an io.Reader failing, aren't about a row and may
+// happen again on every read, so they stop processing.
+func MaxErrorRate(pct float64, minRows int) ErrorFunc {
+	var shared errorBudget
+	return func(ctx context.Context, err error) error {
+		var pe *PositionError
+		if !errors.As(err, &pe) {
+			if !isRowError(err) {
+				return err
+			}
+			return nil
+		}
+		budget := lockBudget(ctx, &shared)
+		defer budget.mu.Unlock()
+		budget.add(err)
+		if budget.rows >= minRows && float64(budget.errors)*100 > pct*float64(budget.rows) {
+			return budget.exceeded(fmt.Sprintf("%g%% of rows", pct), err)
+		}
+		return nil
+	}
+}
+
+// errorBudget counts the errors, and the rows read, seen by an
+// ErrorFunc.
+type errorBudget struct {
+	mu     sync.Mutex
+	errors int
+	rows   int
+}
+
+func (b *errorBudget) add(err error) {
+	b.errors++
+	var pe *PositionError
+	if errors.As(err, &pe) && pe.Index+1 > b.rows {
+		b.rows = pe.Index + 1
+	}
+}
+
+func (b *errorBudget) exceeded(limit string, err error) error {
+	return &BudgetError{Errors: b.errors, Rows: b.rows, Limit: limit, Err: err}
+}
+
+// errorScope holds the budgets of the ErrorFuncs used by one call of
+// ReadAllCallback, by the budget they use outside it.
+type errorScope struct {
+	mu      sync.Mutex
+	budgets map[*errorBudget]*errorBudget
+}
+
+type errorScopeKey struct{}
+
+// withErrorScope returns a copy of ctx with a new errorScope.
+func withErrorScope(ctx context.Context) context.Context {
+	return context.WithValue(ctx, errorScopeKey{}, &errorScope{budgets: make(map[*errorBudget]*errorBudget)})
+}
+
+// lockBudget returns the budget for shared in ctx's errorScope, or
+// shared itself if ctx has none, locked.
+func lockBudget(ctx context.Context, shared *errorBudget) *errorBudget {
+	budget := shared
+	if scope, ok := ctx.Value(errorScopeKey{}).(*errorScope); ok {
+		scope.mu.Lock()
+		budget = scope.budgets[shared]
+		if budget == nil {
+			budget = new(errorBudget)
+			scope.budgets[shared] = budget
+		}
+		scope.mu.Unlock()
+	}
+	budget.mu.Lock()
+	return budget
+}
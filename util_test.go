@@ -0,0 +1,162 @@
+package commando
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// budgetCSV has a bad row after every good one.
+func budgetCSV(rows int) string {
+	var b strings.Builder
+	b.WriteString("name,age\n")
+	for i := 0; i < rows; i++ {
+		fmt.Fprintf(&b, "ok,%d\nbad,x\n", i)
+	}
+	return b.String()
+}
+
+func TestMaxErrors(t *testing.T) {
+	t.Parallel()
+
+	um, err := NewUnmarshaller(metaStruct{}, csv.NewReader(strings.NewReader(budgetCSV(5))))
+	require.NoError(t, err)
+
+	var errs []error
+	out, err := um.ReadAll(context.Background(), ChainErrors(CollectErrors(&errs), MaxErrors(2)))
+	assert.EqualError(t, err, `3 errors in 6 rows, more than the 2 errors tolerated: on line 7, column 5: cannot assign field "age" at index 1 through index chain [1]: strconv.ParseInt: parsing "x": invalid syntax`)
+	assert.Len(t, out, 3)
+	assert.Len(t, errs, 3)
+
+	var be *BudgetError
+	require.True(t, errors.As(err, &be))
+	assert.Equal(t, 3, be.Errors)
+	assert.Equal(t, 6, be.Rows)
+	assert.True(t, errors.Is(err, errs[2]))
+}
+
+func TestMaxErrorRate(t *testing.T) {
+	t.Parallel()
+
+	// Half the rows are bad, but the first 6 are tolerated.
+	um, err := NewUnmarshaller(metaStruct{}, csv.NewReader(strings.NewReader(budgetCSV(5))))
+	require.NoError(t, err)
+	_, err = um.ReadAll(context.Background(), MaxErrorRate(40, 6))
+	assert.EqualError(t, err, `3 errors in 6 rows, more than the 40% of rows tolerated: on line 7, column 5: cannot assign field "age" at index 1 through index chain [1]: strconv.ParseInt: parsing "x": invalid syntax`)
+
+	um, err = NewUnmarshaller(metaStruct{}, csv.NewReader(strings.NewReader(budgetCSV(5))))
+	require.NoError(t, err)
+	out, err := um.ReadAll(context.Background(), MaxErrorRate(50, 1))
+	require.NoError(t, err)
+	assert.Len(t, out, 5)
+}
+
+func TestMaxErrorRate_ReaderErrors(t *testing.T) {
+	t.Parallel()
+
+	// The Reader's errors have no row to put them in proportion to,
+	// so they're left out.
+	reader := csv.NewReader(strings.NewReader("name,age\na\"b,1\nann,1\nbo,2\n"))
+	um, err := NewUnmarshaller(metaStruct{}, reader)
+	require.NoError(t, err)
+	out, err := um.ReadAll(context.Background(), MaxErrorRate(10, 0))
+	require.NoError(t, err)
+	assert.Len(t, out, 2)
+	assert.Equal(t, 1, um.Stats().Skipped)
+}
+
+func TestMaxErrorRate_ReaderFails(t *testing.T) {
+	t.Parallel()
+
+	// A csv.Reader repeats the error of an io.Reader which fails, so
+	// it stops processing rather than being tolerated forever.
+	broken := errors.New("broken")
+	reader := csv.NewReader(io.MultiReader(strings.NewReader("name,age\nann,1\n"), iotest.ErrReader(broken)))
+	um, err := NewUnmarshaller(metaStruct{}, reader)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, err := um.ReadAll(ctx, MaxErrorRate(10, 0))
+	assert.Equal(t, broken, err)
+	assert.Len(t, out, 1)
+}
+
+func TestConfig_ErrorHandler(t *testing.T) {
+	t.Parallel()
+
+	var errs []error
+	c := &Config{Holder: metaStruct{}, ErrorHandler: func(err error) error {
+		errs = append(errs, err)
+		return nil
+	}}
+	um, err := c.NewUnmarshaller(csv.NewReader(strings.NewReader(budgetCSV(5))))
+	require.NoError(t, err)
+	out, err := um.ReadAll(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Len(t, out, 5)
+	assert.Len(t, errs, 5)
+
+	um, err = NewUnmarshaller(metaStruct{}, csv.NewReader(strings.NewReader(budgetCSV(5))))
+	require.NoError(t, err)
+	out, err = um.ReadAll(context.Background(), nil)
+	assert.EqualError(t, err, `on line 3, column 5: cannot assign field "age" at index 1 through index chain [1]: strconv.ParseInt: parsing "x": invalid syntax`)
+	assert.Len(t, out, 1)
+}
+
+func TestConfig_OnError(t *testing.T) {
+	t.Parallel()
+
+	// Every import using the Config has its own budget, whether
+	// they run one after the other or at once.
+	var errs []error
+	for _, workers := range []int{0, 3} {
+		c := &Config{Holder: metaStruct{}, Workers: workers, OnError: ChainErrors(CollectErrors(&errs), MaxErrors(4))}
+		for i := 0; i < 2; i++ {
+			um, err := c.NewUnmarshaller(csv.NewReader(strings.NewReader(budgetCSV(5))))
+			require.NoError(t, err)
+			out, err := um.ReadAll(context.Background(), nil)
+			assert.EqualError(t, err, `5 errors in 10 rows, more than the 4 errors tolerated: on line 11, column 5: cannot assign field "age" at index 1 through index chain [1]: strconv.ParseInt: parsing "x": invalid syntax`)
+			assert.Len(t, out, 5)
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				um, err := c.NewUnmarshaller(csv.NewReader(strings.NewReader(budgetCSV(5))))
+				if !assert.NoError(t, err) {
+					return
+				}
+				_, err = um.ReadAll(context.Background(), nil)
+				var be *BudgetError
+				if assert.True(t, errors.As(err, &be)) {
+					assert.Equal(t, 5, be.Errors)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+	assert.Len(t, errs, 2*6*5)
+
+	// So does each call of ReadAllCallback with the same ErrorFunc.
+	maxErrors := MaxErrors(4)
+	for i := 0; i < 2; i++ {
+		um, err := NewUnmarshaller(metaStruct{}, csv.NewReader(strings.NewReader(budgetCSV(5))))
+		require.NoError(t, err)
+		out, err := um.ReadAll(context.Background(), maxErrors)
+		assert.Error(t, err)
+		assert.Len(t, out, 5)
+	}
+}
@@ -0,0 +1,126 @@
+package commando
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Variant is a type of row in a file which interleaves several types
+// of rows, such as the header, detail and trailer rows of a bank
+// file.
+type Variant struct {
+	// Holder is the struct rows of this type are unmarshalled into,
+	// and marshalled from.
+	Holder interface{}
+
+	// Headers name the columns of rows of this type, in order.  If
+	// nil, the columns are the Holder's fields, in order.
+	Headers []string
+}
+
+var interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// validateVariants is validate for Configs with Variants.  Each
+// variant is validated like a Config of its own, against its own
+// headers.
+func (c *Config) validateVariants() (*validConfig, error) {
+	keys := make([]string, 0, len(c.Variants))
+	for key := range c.Variants {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	vc := &validConfig{
+		Config:      *c,
+		outType:     interfaceType,
+		variants:    make(map[string]*validConfig, len(keys)),
+		variantKeys: make(map[reflect.Type]string, len(keys)),
+	}
+	for _, key := range keys {
+		variant := c.Variants[key]
+		headers := variant.Headers
+		if headers == nil {
+			structInfo, err := getHolderStructInfo(variant.Holder)
+			if err != nil {
+				return nil, fmt.Errorf("variant %q: %v", key, err)
+			}
+			for _, field := range structInfo.Fields {
+				headers = append(headers, field.getFirstKey())
+			}
+		}
+
+		variantConfig := *c
+		variantConfig.Holder = variant.Holder
+		variantConfig.Variants = nil
+		variantVC, err := variantConfig.validate(headers)
+		if err != nil {
+			return nil, fmt.Errorf("variant %q: %v", key, err)
+		}
+		if other, ok := vc.variantKeys[variantVC.outType]; ok {
+			return nil, fmt.Errorf("variants %q and %q have the same holder %s", other, key, variantVC.outType)
+		}
+		vc.variants[key] = variantVC
+		vc.variantKeys[variantVC.outType] = key
+	}
+	return vc, nil
+}
+
+// variantFor returns the validConfig for row, which is vc itself
+// unless vc has Variants.
+func (vc *validConfig) variantFor(row []string) (*validConfig, error) {
+	if vc.variants == nil {
+		return vc, nil
+	}
+	if vc.DiscriminatorIndex >= len(row) {
+		return nil, fmt.Errorf("row has no discriminator at index %d", vc.DiscriminatorIndex)
+	}
+	key := strings.TrimSpace(row[vc.DiscriminatorIndex])
+	variant, ok := vc.variants[key]
+	if !ok {
+		return nil, fmt.Errorf("unknown row type %q", key)
+	}
+	return variant, nil
+}
+
+// encodeVariant returns the row for record, which must be the holder
+// of one of vc's Variants.  Its columns are in the variant's header
+// order, and its discriminator is the variant's key.
+func (vc *validConfig) encodeVariant(record interface{}) ([]string, error) {
+	key, ok := vc.variantKeys[reflect.TypeOf(record)]
+	if !ok {
+		types := make([]string, 0, len(vc.variantKeys))
+		for t := range vc.variantKeys {
+			types = append(types, t.String())
+		}
+		sort.Strings(types)
+		return nil, fmt.Errorf("Expected one of %q, but got %q", types, reflect.TypeOf(record))
+	}
+	variant := vc.variants[key]
+	record, err := variant.beforeMarshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	inValue, _ := getConcreteReflectValueAndType(record)
+	row := make([]string, len(variant.headers))
+	for j, fieldInfo := range variant.fieldInfoMap {
+		if fieldInfo == nil {
+			continue
+		}
+		field, ok := fieldForRead(inValue, fieldInfo.IndexChain)
+		if !ok {
+			continue
+		}
+		value, err := fieldInfo.encode(field)
+		if err != nil {
+			return nil, err
+		}
+		row[j] = value
+	}
+	if vc.DiscriminatorIndex < len(row) {
+		row[vc.DiscriminatorIndex] = key
+	}
+	return row, nil
+}
@@ -0,0 +1,131 @@
+package commando
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bankHeader struct {
+	Type    string `csv:"type"`
+	Bank    string `csv:"bank"`
+	Created string `csv:"created"`
+}
+
+type bankDetail struct {
+	Type    string  `csv:"type"`
+	Account string  `csv:"account"`
+	Amount  float64 `csv:"amount"`
+}
+
+type bankTrailer struct {
+	Type  string `csv:"type"`
+	Count int    `csv:"count"`
+	Total int    `csv:"total"`
+}
+
+var bankVariants = map[string]Variant{
+	"H": {Holder: bankHeader{}},
+	"D": {Holder: &bankDetail{}, Headers: []string{"type", "amount", "account"}},
+	"T": {Holder: bankTrailer{}},
+}
+
+const bankCSV = `H,ACME,2020-01-02
+D,12.5,FR76
+D,3,DE89
+X,what
+D,many,GB12
+T,2,15
+`
+
+func TestVariants_Unmarshal(t *testing.T) {
+	t.Parallel()
+
+	reader := csv.NewReader(strings.NewReader(bankCSV))
+	reader.FieldsPerRecord = -1
+	um, err := (&Config{Variants: bankVariants}).NewUnmarshaller(reader)
+	require.NoError(t, err)
+
+	var errs []string
+	out, err := um.ReadAll(context.Background(), func(_ context.Context, err error) error {
+		errs = append(errs, err.Error())
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		bankHeader{Type: "H", Bank: "ACME", Created: "2020-01-02"},
+		&bankDetail{Type: "D", Account: "FR76", Amount: 12.5},
+		&bankDetail{Type: "D", Account: "DE89", Amount: 3},
+		bankTrailer{Type: "T", Count: 2, Total: 15},
+	}, out)
+	assert.Equal(t, []string{
+		`on line 4: unknown row type "X"`,
+		`on line 5, column 3: cannot assign field "amount" at index 1 through index chain [2]: strconv.ParseFloat: parsing "many": invalid syntax`,
+	}, errs)
+
+	assert.EqualError(t, um.ReadInto(&bankHeader{}), "ReadInto can't be used with Variants, use Read")
+}
+
+func TestVariants_DiscriminatorIndex(t *testing.T) {
+	t.Parallel()
+
+	type first struct {
+		Name string `csv:"name"`
+		Kind string `csv:"kind"`
+	}
+	type second struct {
+		Value int    `csv:"value"`
+		Kind  string `csv:"kind"`
+	}
+	c := &Config{
+		Variants:           map[string]Variant{"1": {Holder: first{}}, "2": {Holder: second{}}},
+		DiscriminatorIndex: 1,
+	}
+	um, err := c.NewUnmarshaller(csv.NewReader(strings.NewReader("a,1\n42,2\n")))
+	require.NoError(t, err)
+
+	out, err := um.Read()
+	require.NoError(t, err)
+	assert.Equal(t, first{Name: "a", Kind: "1"}, out)
+	out, err = um.Read()
+	require.NoError(t, err)
+	assert.Equal(t, second{Value: 42, Kind: "2"}, out)
+}
+
+func TestVariants_Marshal(t *testing.T) {
+	t.Parallel()
+
+	buf := new(bytes.Buffer)
+	m, err := (&Config{Variants: bankVariants}).NewMarshaller(csv.NewWriter(buf))
+	require.NoError(t, err)
+
+	require.NoError(t, m.Write(bankHeader{Bank: "ACME", Created: "2020-01-02"}))
+	require.NoError(t, m.WriteAll([]interface{}{
+		&bankDetail{Account: "FR76", Amount: 12.5},
+		bankTrailer{Count: 1, Total: 12},
+	}))
+	assert.EqualError(t, m.Write(bankDetail{}), `Expected one of ["*commando.bankDetail" "commando.bankHeader" "commando.bankTrailer"], but got "commando.bankDetail"`)
+	require.NoError(t, m.Flush())
+
+	assert.Equal(t, "H,ACME,2020-01-02\nD,12.5,FR76\nT,1,12\n", buf.String())
+}
+
+func TestVariants_Validate(t *testing.T) {
+	t.Parallel()
+
+	_, err := (&Config{Variants: map[string]Variant{
+		"A": {Holder: bankHeader{}},
+		"B": {Holder: bankHeader{}},
+	}}).NewUnmarshaller(csv.NewReader(strings.NewReader("")))
+	assert.EqualError(t, err, `variants "A" and "B" have the same holder commando.bankHeader`)
+
+	_, err = (&Config{Variants: map[string]Variant{
+		"A": {Holder: bankHeader{}, Headers: []string{"nope"}},
+	}}).NewUnmarshaller(csv.NewReader(strings.NewReader("")))
+	assert.EqualError(t, err, `variant "A": expected one or more of headers [type bank created], but got [nope] `)
+}
@@ -0,0 +1,15 @@
+package commando
+
+// Writer is an interface over csv.Writer, which allows swapping the
+// implementation, if necessary.
+type Writer interface {
+	Write(record []string) error
+	Flush()
+	Error() error
+}
+
+// headerless is implemented by Readers and Writers for formats which
+// have no header row.  The header is implied by the layout instead.
+type headerless interface {
+	headers() []string
+}
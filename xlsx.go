@@ -0,0 +1,526 @@
+package commando
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	xlsxMainNS       = "http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+	xlsxRelsNS       = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	xlsxPackageNS    = "http://schemas.openxmlformats.org/package/2006/relationships"
+	xlsxWorksheet    = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet"
+	xlsxOfficeDoc    = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument"
+	xlsxDefaultSheet = "Sheet1"
+)
+
+// ErrSheetNotFound is returned by NewXLSXReader when the workbook has
+// no sheet with the requested name.
+var ErrSheetNotFound = errors.New("sheet not found")
+
+// --------------------------------------------------------------------------
+// Package parts
+
+type xlsxWorkbook struct {
+	Properties struct {
+		Date1904 bool `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText is a run of text, either plain or rich, as found in shared
+// strings and inline strings.
+type xlsxText struct {
+	T    *string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if t.T != nil {
+		return *t.T
+	}
+	var b strings.Builder
+	for _, r := range t.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxStyles struct {
+	NumFmts []struct {
+		ID   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellXfs []struct {
+		NumFmtID int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+type xlsxRow struct {
+	R     int        `xml:"r,attr"`
+	Cells []xlsxCell `xml:"c"`
+}
+
+type xlsxCell struct {
+	R  string    `xml:"r,attr"`
+	T  string    `xml:"t,attr"`
+	S  int       `xml:"s,attr"`
+	V  string    `xml:"v"`
+	Is *xlsxText `xml:"is"`
+}
+
+func readXLSXPart(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("parsing %s: %w", name, err)
+	}
+	return nil
+}
+
+// isDateFormat reports whether a number format displays a date or
+// time.
+func isDateFormat(id int, code string) bool {
+	if (id >= 14 && id <= 22) || (id >= 45 && id <= 47) {
+		return true
+	}
+	if code == "" {
+		return false
+	}
+
+	// Ignore quoted literals and [bracketed] colors and conditions.
+	inQuote, inBracket := false, false
+	for _, r := range strings.ToLower(code) {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case r == '[':
+			inBracket = true
+		case r == ']':
+			inBracket = false
+		case inBracket:
+		case strings.ContainsRune("ymdhs", r):
+			return true
+		}
+	}
+	return false
+}
+
+// xlsxMaxColumns is the number of columns a sheet may have, up to
+// XFD.
+const xlsxMaxColumns = 16384
+
+// columnIndex returns the zero-based column of a cell reference such
+// as "AB12", or -1 if ref has no column.  Columns past XFD are
+// returned as xlsxMaxColumns.
+func columnIndex(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+		if col > xlsxMaxColumns {
+			return xlsxMaxColumns
+		}
+	}
+	return col - 1
+}
+
+// columnName returns the letters of the zero-based column col.
+func columnName(col int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name
+}
+
+// --------------------------------------------------------------------------
+// Reader
+
+// XLSXReader is a Reader for one sheet of an .xlsx workbook.
+//
+// Each row of the sheet is returned as a record.  Shared and inline
+// strings are returned as-is, numbers as written in the file, and
+// booleans as "true" or "false".  Numbers formatted as dates are
+// converted from Excel serials and formatted with DateLayout.
+//
+// XLSXReader is a PositionedReader whose lines are the sheet's row
+// numbers and whose columns are its column numbers, counting A as 1,
+// so that errors name the cells people see.  It has no byte offsets,
+// so it can't be checkpointed.
+type XLSXReader struct {
+	// DateLayout is the layout used to format date cells.  It
+	// defaults to time.RFC3339, which time.Time fields accept.
+	DateLayout string
+
+	sharedStrings []string
+	dateStyles    []bool
+	epoch         time.Time
+
+	sheet   io.ReadCloser
+	decoder *xml.Decoder
+	width   int
+
+	// row is the number of the row most recently read.
+	row int
+}
+
+// NewXLSXReader returns an XLSXReader for the named sheet of the
+// workbook in r, which has the given size.  If sheet is empty, the
+// first sheet is read.
+//
+// Close must be called when reading is complete.
+func NewXLSXReader(r io.ReaderAt, size int64, sheet string) (*XLSXReader, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var workbook xlsxWorkbook
+	if err := readXLSXPart(files, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	var rels xlsxRelationships
+	if err := readXLSXPart(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	var sst xlsxSharedStrings
+	if err := readXLSXPart(files, "xl/sharedStrings.xml", &sst); err != nil {
+		return nil, err
+	}
+	var styles xlsxStyles
+	if err := readXLSXPart(files, "xl/styles.xml", &styles); err != nil {
+		return nil, err
+	}
+
+	relID := ""
+	for _, s := range workbook.Sheets {
+		if sheet == "" || s.Name == sheet {
+			relID = s.ID
+			break
+		}
+	}
+	target := ""
+	for _, rel := range rels.Relationships {
+		if relID != "" && rel.ID == relID {
+			target = rel.Target
+		}
+	}
+	if target == "" {
+		return nil, fmt.Errorf("%w: %q", ErrSheetNotFound, sheet)
+	}
+	if strings.HasPrefix(target, "/") {
+		target = target[1:]
+	} else {
+		target = path.Join("xl", target)
+	}
+	f, ok := files[target]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q has no part %s", ErrSheetNotFound, sheet, target)
+	}
+
+	xr := &XLSXReader{
+		DateLayout: time.RFC3339,
+		epoch:      time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC),
+	}
+	if workbook.Properties.Date1904 {
+		xr.epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	for _, si := range sst.Items {
+		xr.sharedStrings = append(xr.sharedStrings, si.String())
+	}
+	customFormats := make(map[int]string, len(styles.NumFmts))
+	for _, numFmt := range styles.NumFmts {
+		customFormats[numFmt.ID] = numFmt.Code
+	}
+	for _, xf := range styles.CellXfs {
+		xr.dateStyles = append(xr.dateStyles, isDateFormat(xf.NumFmtID, customFormats[xf.NumFmtID]))
+	}
+
+	if xr.sheet, err = f.Open(); err != nil {
+		return nil, err
+	}
+	xr.decoder = xml.NewDecoder(xr.sheet)
+	return xr, nil
+}
+
+// Read returns the cells of the next non-empty row.  Rows are padded
+// with empty cells to the width of the first row.
+func (r *XLSXReader) Read() ([]string, error) {
+	for {
+		tok, err := r.decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+
+		var row xlsxRow
+		if err := r.decoder.DecodeElement(&row, &start); err != nil {
+			return nil, err
+		}
+		// Rows without a number follow the previous one.
+		if row.R > 0 {
+			r.row = row.R
+		} else {
+			r.row++
+		}
+		if len(row.Cells) == 0 {
+			continue
+		}
+
+		record := make([]string, r.width)
+		for i, c := range row.Cells {
+			col := columnIndex(c.R)
+			if col < 0 {
+				col = i
+			}
+			if col >= xlsxMaxColumns {
+				return nil, fmt.Errorf("cell %s: column is past XFD", c.R)
+			}
+			for len(record) <= col {
+				record = append(record, "")
+			}
+			if record[col], err = r.cellValue(c); err != nil {
+				return nil, fmt.Errorf("cell %s: %w", c.R, err)
+			}
+		}
+		if r.width == 0 {
+			r.width = len(record)
+		}
+		return record, nil
+	}
+}
+
+// FieldPos returns the row number of the row most recently read, and
+// the column number of the given field.
+func (r *XLSXReader) FieldPos(field int) (line, column int) {
+	return r.row, field + 1
+}
+
+// InputOffset returns -1, since sheets have no byte offsets.
+func (r *XLSXReader) InputOffset() int64 {
+	return -1
+}
+
+func (r *XLSXReader) cellValue(c xlsxCell) (string, error) {
+	switch c.T {
+	case "s":
+		i, err := strconv.Atoi(c.V)
+		if err != nil || i < 0 || i >= len(r.sharedStrings) {
+			return "", fmt.Errorf("invalid shared string %q", c.V)
+		}
+		return r.sharedStrings[i], nil
+	case "inlineStr":
+		if c.Is == nil {
+			return "", nil
+		}
+		return c.Is.String(), nil
+	case "b":
+		if c.V == "1" {
+			return "true", nil
+		}
+		return "false", nil
+	case "", "n":
+		if c.V == "" || c.S < 0 || c.S >= len(r.dateStyles) || !r.dateStyles[c.S] {
+			return c.V, nil
+		}
+		serial, err := strconv.ParseFloat(c.V, 64)
+		if err != nil {
+			return "", err
+		}
+		t := r.epoch.Add(time.Duration(math.Round(serial*86400)) * time.Second)
+		return t.Format(r.DateLayout), nil
+	}
+	// Formula strings ("str"), ISO dates ("d") and errors ("e") are
+	// returned as written.
+	return c.V, nil
+}
+
+// Close closes the sheet being read.
+func (r *XLSXReader) Close() error {
+	return r.sheet.Close()
+}
+
+// --------------------------------------------------------------------------
+// Writer
+
+// XLSXWriter is a Writer which writes records to a single sheet of an
+// .xlsx workbook.
+//
+// Values which are plain decimal numbers are written as numeric cells,
+// and everything else as inline strings.
+//
+// Close must be called when writing is complete to finish the
+// workbook.
+type XLSXWriter struct {
+	zip    *zip.Writer
+	sheet  io.Writer
+	row    int
+	err    error
+	closed bool
+}
+
+// NewXLSXWriter returns an XLSXWriter which writes a workbook with one
+// sheet, named sheet, to w.  If sheet is empty, it is named "Sheet1".
+func NewXLSXWriter(w io.Writer, sheet string) (*XLSXWriter, error) {
+	if sheet == "" {
+		sheet = xlsxDefaultSheet
+	}
+	if len(sheet) > 31 || strings.ContainsAny(sheet, `[]:*?/\`) {
+		return nil, fmt.Errorf("invalid sheet name %q", sheet)
+	}
+
+	var name strings.Builder
+	if err := xml.EscapeText(&name, []byte(sheet)); err != nil {
+		return nil, err
+	}
+
+	xw := &XLSXWriter{zip: zip.NewWriter(w)}
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+			`</Types>`},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="` + xlsxPackageNS + `">` +
+			`<Relationship Id="rId1" Type="` + xlsxOfficeDoc + `" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", xml.Header + `<workbook xmlns="` + xlsxMainNS + `" xmlns:r="` + xlsxRelsNS + `">` +
+			`<sheets><sheet name="` + name.String() + `" sheetId="1" r:id="rId1"/></sheets>` +
+			`</workbook>`},
+		{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="` + xlsxPackageNS + `">` +
+			`<Relationship Id="rId1" Type="` + xlsxWorksheet + `" Target="worksheets/sheet1.xml"/>` +
+			`</Relationships>`},
+	}
+	for _, part := range parts {
+		pw, err := xw.zip.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(pw, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	sw, err := xw.zip.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	xw.sheet = sw
+	if _, err := io.WriteString(sw, xml.Header+`<worksheet xmlns="`+xlsxMainNS+`"><sheetData>`); err != nil {
+		return nil, err
+	}
+	return xw, nil
+}
+
+// Write writes record as the next row of the sheet.
+func (w *XLSXWriter) Write(record []string) error {
+	if w.closed {
+		return errXLSXClosed
+	}
+	if w.err != nil {
+		return w.err
+	}
+
+	w.row++
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d">`, w.row)
+	for i, value := range record {
+		ref := columnName(i) + strconv.Itoa(w.row)
+		if isXLSXNumber(value) {
+			fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, value)
+			continue
+		}
+		fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+		if w.err = xml.EscapeText(&b, []byte(value)); w.err != nil {
+			return w.err
+		}
+		b.WriteString(`</t></is></c>`)
+	}
+	b.WriteString(`</row>`)
+
+	_, w.err = io.WriteString(w.sheet, b.String())
+	return w.err
+}
+
+// isXLSXNumber reports whether value can be written as a numeric cell
+// and read back unchanged.
+func isXLSXNumber(value string) bool {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return false
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64) == value
+}
+
+// Flush writes any buffered data to the underlying io.Writer.  To
+// check if an error occurred during the Flush, call Error.
+func (w *XLSXWriter) Flush() {
+	if w.err == nil && !w.closed {
+		w.err = w.zip.Flush()
+	}
+}
+
+// Error reports any error that has occurred during a previous Write,
+// Flush or Close.
+func (w *XLSXWriter) Error() error {
+	return w.err
+}
+
+// Close finishes the sheet and the workbook.  It doesn't close the
+// underlying io.Writer.
+func (w *XLSXWriter) Close() error {
+	if w.closed || w.err != nil {
+		return w.err
+	}
+	w.closed = true
+	if _, w.err = io.WriteString(w.sheet, `</sheetData></worksheet>`); w.err != nil {
+		return w.err
+	}
+	w.err = w.zip.Close()
+	return w.err
+}
+
+var errXLSXClosed = errors.New("xlsx writer is closed")
@@ -0,0 +1,177 @@
+package commando
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type xlsxSample struct {
+	Name    string    `csv:"name"`
+	Qty     int       `csv:"qty"`
+	Price   float64   `csv:"price"`
+	Active  bool      `csv:"active"`
+	Created time.Time `csv:"created"`
+}
+
+// buildXLSX returns a workbook containing the given parts.
+func buildXLSX(t *testing.T, parts map[string]string) *bytes.Reader {
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = io.WriteString(w, content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestXLSXReader(t *testing.T) {
+	t.Parallel()
+
+	r := buildXLSX(t, map[string]string{
+		"xl/workbook.xml": xml.Header + `<workbook xmlns="` + xlsxMainNS + `" xmlns:r="` + xlsxRelsNS + `">` +
+			`<sheets><sheet name="Summary" sheetId="1" r:id="rId1"/><sheet name="Orders" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": xml.Header + `<Relationships xmlns="` + xlsxPackageNS + `">` +
+			`<Relationship Id="rId1" Type="` + xlsxWorksheet + `" Target="worksheets/sheet1.xml"/>` +
+			`<Relationship Id="rId2" Type="` + xlsxWorksheet + `" Target="/xl/worksheets/sheet2.xml"/>` +
+			`</Relationships>`,
+		"xl/sharedStrings.xml": xml.Header + `<sst xmlns="` + xlsxMainNS + `">` +
+			`<si><t>name</t></si><si><t>qty</t></si><si><t>price</t></si><si><t>active</t></si><si><t>created</t></si>` +
+			`<si><r><t>Wid</t></r><r><t>get</t></r></si>` +
+			`</sst>`,
+		"xl/styles.xml": xml.Header + `<styleSheet xmlns="` + xlsxMainNS + `">` +
+			`<numFmts><numFmt numFmtId="164" formatCode="yyyy\-mm\-dd\ hh:mm"/></numFmts>` +
+			`<cellXfs><xf numFmtId="0"/><xf numFmtId="14"/><xf numFmtId="164"/></cellXfs>` +
+			`</styleSheet>`,
+		"xl/worksheets/sheet1.xml": xml.Header + `<worksheet xmlns="` + xlsxMainNS + `"><sheetData/></worksheet>`,
+		"xl/worksheets/sheet2.xml": xml.Header + `<worksheet xmlns="` + xlsxMainNS + `"><sheetData>` +
+			`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="s"><v>2</v></c><c r="D1" t="s"><v>3</v></c><c r="E1" t="s"><v>4</v></c></row>` +
+			`<row r="2"><c r="A2" t="s"><v>5</v></c><c r="B2"><v>3</v></c><c r="C2"><v>9.5</v></c><c r="D2" t="b"><v>1</v></c><c r="E2" s="1"><v>44197</v></c></row>` +
+			`<row r="3"><c r="A3" t="inlineStr"><is><t>Gadget</t></is></c><c r="C3"><v>12</v></c><c r="E3" s="2"><v>44197.5</v></c></row>` +
+			`<row r="5"/>` +
+			`</sheetData></worksheet>`,
+	})
+
+	xr, err := NewXLSXReader(r, r.Size(), "Orders")
+	require.NoError(t, err)
+	defer xr.Close()
+
+	um, err := NewUnmarshaller(xlsxSample{}, xr)
+	require.NoError(t, err)
+
+	out, err := um.ReadAll(context.Background(), StopOnError)
+	require.NoError(t, err)
+	assert.Equal(t, []xlsxSample{
+		{"Widget", 3, 9.5, true, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"Gadget", 0, 12, false, time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)},
+	}, out)
+
+	_, err = NewXLSXReader(r, r.Size(), "Missing")
+	assert.True(t, errors.Is(err, ErrSheetNotFound), "Expected ErrSheetNotFound, got %v", err)
+}
+
+func TestXLSXWriter(t *testing.T) {
+	t.Parallel()
+
+	in := []xlsxSample{
+		{"Widget", 3, 9.5, true, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"<Gadget & co>", 0, 12, false, time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{"007", -1, 0.25, false, time.Time{}},
+	}
+
+	buf := new(bytes.Buffer)
+	w, err := NewXLSXWriter(buf, "Orders")
+	require.NoError(t, err)
+
+	m, err := NewMarshaller(xlsxSample{}, w)
+	require.NoError(t, err)
+	require.NoError(t, m.WriteAll(in))
+	require.NoError(t, m.Flush())
+	require.NoError(t, w.Close())
+	assert.Error(t, w.Write([]string{"late"}), "Expected an error writing after Close")
+
+	r := bytes.NewReader(buf.Bytes())
+	xr, err := NewXLSXReader(r, r.Size(), "")
+	require.NoError(t, err)
+	defer xr.Close()
+
+	um, err := NewUnmarshaller(xlsxSample{}, xr)
+	require.NoError(t, err)
+
+	out, err := um.ReadAll(context.Background(), StopOnError)
+	require.NoError(t, err)
+	assert.Equal(t, in, out)
+}
+
+// sheetXLSX returns a workbook whose only sheet has the given
+// sheetData.
+func sheetXLSX(t *testing.T, sheetData string) *XLSXReader {
+	r := buildXLSX(t, map[string]string{
+		"xl/workbook.xml": xml.Header + `<workbook xmlns="` + xlsxMainNS + `" xmlns:r="` + xlsxRelsNS + `">` +
+			`<sheets><sheet name="Orders" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": xml.Header + `<Relationships xmlns="` + xlsxPackageNS + `">` +
+			`<Relationship Id="rId1" Type="` + xlsxWorksheet + `" Target="worksheets/sheet1.xml"/>` +
+			`</Relationships>`,
+		"xl/worksheets/sheet1.xml": xml.Header + `<worksheet xmlns="` + xlsxMainNS + `"><sheetData>` + sheetData + `</sheetData></worksheet>`,
+	})
+	xr, err := NewXLSXReader(r, r.Size(), "")
+	require.NoError(t, err)
+	t.Cleanup(func() { xr.Close() })
+	return xr
+}
+
+func TestXLSXReader_Positions(t *testing.T) {
+	t.Parallel()
+
+	// Errors name the sheet's rows and columns, even after blank
+	// rows.
+	xr := sheetXLSX(t, `<row r="1"><c r="A1" t="inlineStr"><is><t>name</t></is></c><c r="B1" t="inlineStr"><is><t>qty</t></is></c></row>`+
+		`<row r="2"/>`+
+		`<row r="4"><c r="A4" t="inlineStr"><is><t>Widget</t></is></c><c r="B4"><v>3</v></c></row>`+
+		`<row r="7"><c r="A7" t="inlineStr"><is><t>Gadget</t></is></c><c r="B7" t="inlineStr"><is><t>many</t></is></c></row>`)
+	um, err := NewUnmarshaller(xlsxSample{}, xr)
+	require.NoError(t, err)
+
+	_, meta, err := um.ReadWithMeta()
+	require.NoError(t, err)
+	assert.Equal(t, 4, meta.StartLine)
+	_, err = um.Read()
+	assert.EqualError(t, err, `on line 7, column 2: cannot assign field "qty" at index 1 through index chain [1]: strconv.ParseInt: parsing "many": invalid syntax`)
+
+	_, err = um.Checkpoint()
+	assert.Equal(t, ErrNoOffset, err)
+}
+
+func TestXLSXReader_WideColumn(t *testing.T) {
+	t.Parallel()
+
+	xr := sheetXLSX(t, `<row r="1"><c r="ZZZZZZZ1"><v>1</v></c></row>`)
+	_, err := xr.Read()
+	assert.EqualError(t, err, "cell ZZZZZZZ1: column is past XFD")
+
+	xr = sheetXLSX(t, `<row r="1"><c r="XFD1"><v>1</v></c></row>`)
+	record, err := xr.Read()
+	require.NoError(t, err)
+	assert.Len(t, record, xlsxMaxColumns)
+}
+
+func TestXLSXColumns(t *testing.T) {
+	t.Parallel()
+
+	for col, name := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		assert.Equal(t, name, columnName(col))
+		assert.Equal(t, col, columnIndex(name+"12"))
+	}
+	assert.Equal(t, -1, columnIndex("12"))
+}